@@ -13,11 +13,13 @@ type AppConfig struct {
 	GoogleAPIKey       string `json:"google_api_key"`
 	GoogleCX           string `json:"google_cx"`
 	BingAPIKey         string `json:"bing_api_key"`
-	UseMilvus          string `json:"use_milvus"` // 1 表示使用Milvus
+	UseMilvus          string `json:"use_milvus"`   // 1 表示使用Milvus
+	VectorStore        string `json:"vector_store"` // local、chroma 或 milvus，默认 local
 	ChromaPath         string `json:"chroma_path"`
 	ChromaURL          string `json:"chroma_url"`
 	MilvusURL          string `json:"milvus_url"`
-	SQLitePath         string `json:"sqlite_path"` // 新增SQLite路径
+	MilvusToken        string `json:"milvus_token"` // 开启鉴权时为 "用户名:密码" 或 API Key
+	SQLitePath         string `json:"sqlite_path"`  // 新增SQLite路径
 	DefaultModel       string `json:"default_model"`
 	HistoryLimit       int    `json:"history_limit"` // 历史记录条数限制
 	RetentionDays      int    `json:"retention_days"`
@@ -25,6 +27,125 @@ type AppConfig struct {
 	EmbeddingModel     string `json:"embedding_model"`
 	EmbeddingDimension int    `json:"embedding_dimension"`
 	EmbeddingBatchSize int    `json:"embedding_batch_size"`
+	ChunkStrategy      string `json:"chunk_strategy"` // fixed、sentence 或 markdown
+	ChunkSize          int    `json:"chunk_size"`     // 每块字符数
+	ChunkOverlap       int    `json:"chunk_overlap"`  // 相邻块重叠字符数
+	// 导入 DOCX、ODT 时是否同时导入页眉页脚、脚注、尾注和批注，默认只导入正文
+	ParseExtras bool `json:"parse_extras"`
+	// 表格、JSON 每行记录转为文本的模板，如 "商品 {{.SKU}} 的价格为 {{.price}} 元"
+	RowTemplate string `json:"row_template"`
+	// 导入文件夹时同时解析、向量化的文件数，默认 2
+	ImportWorkers int `json:"import_workers"`
+	// 自动同步到知识库的文件夹，启动后监听新增、修改和删除的文件
+	WatchFolders []WatchFolderConfig `json:"watch_folders"`
+	// 知识库检索方式：vector、keyword 或 hybrid，默认 hybrid
+	RetrievalMode string `json:"retrieval_mode"`
+	// 混合检索时向量检索和关键词检索结果的权重，默认均为 1
+	VectorWeight  float64 `json:"vector_weight"`
+	KeywordWeight float64 `json:"keyword_weight"`
+	// 每次对话从知识库取的参考文档数，默认 3
+	KBTopK int `json:"kb_top_k"`
+	// 检索结果的重排方式：为空不重排，llm 由大模型打分，embedding 用 rerank_model 指定的向量模型重新计算相似度
+	RerankMode string `json:"rerank_mode"`
+	// 重排使用的模型，llm 方式为空时使用 default_model
+	RerankModel string `json:"rerank_model"`
+	// 重排或多样化前检索的候选数，默认为 kb_top_k 的 4 倍
+	RerankCandidates int `json:"rerank_candidates"`
+	// 最大边际相关性（MMR）的相关度权重 0-1，越小结果越多样，1 表示不做多样化，默认 0.7
+	MMRLambda float64 `json:"mmr_lambda"`
+	// 检索前结合对话历史把追问改写为独立的问题
+	QueryRewrite bool `json:"query_rewrite"`
+	// 额外生成的同义问法数，各问法分别检索后合并，0 表示不生成
+	QueryExpansions int `json:"query_expansions"`
+	// 让大模型先写一段假设的答案，与问题一起检索（HyDE）
+	HyDE bool `json:"hyde"`
+	// 改写和扩展问题使用的模型，为空时使用当前对话的模型
+	QueryRewriteModel string `json:"query_rewrite_model"`
+
+	// 大模型服务提供方，未配置名为 ollama 的提供方时默认使用 OllamaURL
+	Providers []ProviderConfig `json:"providers"`
+	// 模型名称 -> 提供方名称，未指定的模型按各提供方列出的模型自动匹配
+	ModelProviders map[string]string `json:"model_providers"`
+
+	// 生成参数预设，名称 -> 参数
+	GenerationPresets map[string]GenerationPreset `json:"generation_presets"`
+	DefaultPreset     string                      `json:"default_preset"`
+}
+
+// GenerationPreset 一组命名的生成参数，字段含义与 Ollama options 相同，未设置的字段使用模型默认值。
+// 数值字段使用指针，以便 temperature、seed 等显式设为 0
+type GenerationPreset struct {
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty"`
+	NumPredict    *int     `json:"num_predict,omitempty"`
+	RepeatPenalty *float32 `json:"repeat_penalty,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	Mirostat      *int     `json:"mirostat,omitempty"`
+	MirostatTau   *float32 `json:"mirostat_tau,omitempty"`
+	MirostatEta   *float32 `json:"mirostat_eta,omitempty"`
+	KeepAlive     string   `json:"keep_alive,omitempty"`
+}
+
+// DefaultGenerationPresets 未配置预设时内置的参数组合
+func DefaultGenerationPresets() map[string]GenerationPreset {
+	return map[string]GenerationPreset{
+		"balanced": {Temperature: Ptr[float32](0.7), TopP: Ptr[float32](0.9)},
+		"precise":  {Temperature: Ptr[float32](0.1), TopP: Ptr[float32](0.5), TopK: Ptr(20), RepeatPenalty: Ptr[float32](1.1)},
+		"creative": {Temperature: Ptr[float32](1.0), TopP: Ptr[float32](0.95), TopK: Ptr(80)},
+	}
+}
+
+// Ptr 返回 v 的指针，用于填写可选的参数字段
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// 向量库类型
+const (
+	VectorStoreLocal  = "local"  // 内置向量库，数据保存在 chroma_path 目录下的 SQLite 文件中
+	VectorStoreChroma = "chroma" // 外部 Chroma 服务
+	VectorStoreMilvus = "milvus" // 外部 Milvus 服务，use_milvus 为 1 时同样启用
+)
+
+// 知识库检索方式
+const (
+	RetrievalVector  = "vector"  // 只用向量检索
+	RetrievalKeyword = "keyword" // 只用 BM25 关键词检索
+	RetrievalHybrid  = "hybrid"  // 两者按倒数排名融合（RRF）
+)
+
+// 检索结果的重排方式
+const (
+	RerankNone      = ""
+	RerankLLM       = "llm"
+	RerankEmbedding = "embedding"
+)
+
+// 大模型服务提供方类型
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai" // OpenAI 兼容接口，如 llama.cpp server、vLLM、LM Studio
+)
+
+// WatchFolderConfig 监听的文件夹，Include、Exclude 的写法与导入文件夹相同
+type WatchFolderConfig struct {
+	Path       string   `json:"path"`
+	Collection string   `json:"collection"` // 为空时使用 collection_name
+	Include    []string `json:"include"`
+	Exclude    []string `json:"exclude"`
+	Tags       []string `json:"tags"` // 写入该文件夹中文件的标签
+}
+
+type ProviderConfig struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+	// ContextLength 该提供方模型的上下文长度，0 表示未知；Ollama 的模型从 /api/show 读取，不需要设置
+	ContextLength int `json:"context_length"`
 }
 
 // LoadConfig 解析传入文件名称 ，通过json文件解析 返回appConfig配置及错误
@@ -53,6 +174,45 @@ func LoadConfig(filePath string) (*AppConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file: %v", err)
 	}
 
+	if config.CollectionName == "" {
+		config.CollectionName = "default"
+	}
+	if config.UseMilvus == "1" {
+		config.VectorStore = VectorStoreMilvus
+	}
+	if config.VectorStore == "" {
+		config.VectorStore = VectorStoreLocal
+	}
+
+	if config.RetrievalMode == "" {
+		config.RetrievalMode = RetrievalHybrid
+	}
+	if config.VectorWeight <= 0 {
+		config.VectorWeight = 1
+	}
+	if config.KeywordWeight <= 0 {
+		config.KeywordWeight = 1
+	}
+	if config.KBTopK <= 0 {
+		config.KBTopK = 3
+	}
+	if config.MMRLambda <= 0 || config.MMRLambda > 1 {
+		config.MMRLambda = 0.7
+	}
+	if config.RerankCandidates < config.KBTopK {
+		config.RerankCandidates = config.KBTopK * 4
+	}
+
+	if len(config.GenerationPresets) == 0 {
+		config.GenerationPresets = DefaultGenerationPresets()
+	}
+	if _, ok := config.GenerationPresets[config.DefaultPreset]; !ok {
+		config.DefaultPreset = ""
+		if _, ok := config.GenerationPresets["balanced"]; ok {
+			config.DefaultPreset = "balanced"
+		}
+	}
+
 	log.Printf("Config loaded successfully from %s", filePath)
 	return &config, nil
 }
@@ -2,16 +2,24 @@ package ai_model
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
 )
 
 type OllamaClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
+	// StreamClient 用于流式生成，不设置整体超时，由调用方通过 context 控制取消
+	StreamClient *http.Client
+
+	contextLengths sync.Map // 模型名称 -> /api/show 报告的上下文长度
 }
 
 func NewOllamaClient(baseURL string) *OllamaClient {
@@ -20,19 +28,112 @@ func NewOllamaClient(baseURL string) *OllamaClient {
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		StreamClient: &http.Client{},
 	}
 }
 
 type GenerationRequest struct {
-	Model   string  `json:"model"`
-	Prompt  string  `json:"prompt"`
-	Stream  bool    `json:"stream"`
-	Options Options `json:"options"`
+	Model     string   `json:"model"`
+	Prompt    string   `json:"prompt"`
+	Stream    bool     `json:"stream"`
+	Options   *Options `json:"options,omitempty"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
 }
 
+// Options 生成参数，为 nil 的字段不发送，由模型使用自身默认值；
+// 数值字段使用指针，temperature、seed 等可以显式设为 0
 type Options struct {
-	Temperature float32 `json:"temperature"`
-	TopP        float32 `json:"top_p"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty"`
+	NumPredict    *int     `json:"num_predict,omitempty"`
+	RepeatPenalty *float32 `json:"repeat_penalty,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	Mirostat      *int     `json:"mirostat,omitempty"`
+	MirostatTau   *float32 `json:"mirostat_tau,omitempty"`
+	MirostatEta   *float32 `json:"mirostat_eta,omitempty"`
+	// KeepAlive 模型在内存中保留的时长，如 "5m"、"-1"，作为请求的顶层字段发送
+	KeepAlive string `json:"-"`
+}
+
+// DefaultNumCtx 未设置 num_ctx 时 Ollama 使用的上下文长度
+const DefaultNumCtx = 2048
+
+// DefaultOptions 未指定参数时使用的默认值
+func DefaultOptions() *Options {
+	return &Options{Temperature: Float32(0.7), TopP: Float32(0.9)}
+}
+
+// Float32 返回 v 的指针，用于填写 Options 中的可选字段
+func Float32(v float32) *float32 {
+	return &v
+}
+
+// Int 返回 v 的指针，用于填写 Options 中的可选字段
+func Int(v int) *int {
+	return &v
+}
+
+// IntValue 返回字段的值，未设置时返回 def
+func IntValue(p *int, def int) int {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// NewOptions 根据配置中的预设生成参数
+func NewOptions(p config.GenerationPreset) *Options {
+	return &Options{
+		Temperature:   clone(p.Temperature),
+		TopP:          clone(p.TopP),
+		TopK:          clone(p.TopK),
+		NumCtx:        clone(p.NumCtx),
+		NumPredict:    clone(p.NumPredict),
+		RepeatPenalty: clone(p.RepeatPenalty),
+		Seed:          clone(p.Seed),
+		Stop:          append([]string(nil), p.Stop...),
+		Mirostat:      clone(p.Mirostat),
+		MirostatTau:   clone(p.MirostatTau),
+		MirostatEta:   clone(p.MirostatEta),
+		KeepAlive:     p.KeepAlive,
+	}
+}
+
+// Preset 转换为可保存到配置中的预设
+func (o *Options) Preset() config.GenerationPreset {
+	return config.GenerationPreset{
+		Temperature:   clone(o.Temperature),
+		TopP:          clone(o.TopP),
+		TopK:          clone(o.TopK),
+		NumCtx:        clone(o.NumCtx),
+		NumPredict:    clone(o.NumPredict),
+		RepeatPenalty: clone(o.RepeatPenalty),
+		Seed:          clone(o.Seed),
+		Stop:          append([]string(nil), o.Stop...),
+		Mirostat:      clone(o.Mirostat),
+		MirostatTau:   clone(o.MirostatTau),
+		MirostatEta:   clone(o.MirostatEta),
+		KeepAlive:     o.KeepAlive,
+	}
+}
+
+// clone 复制指针指向的值，避免预设和请求参数共用同一个变量
+func clone[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func orDefault(opts *Options) *Options {
+	if opts == nil {
+		return DefaultOptions()
+	}
+	return opts
 }
 
 type GenerationResponse struct {
@@ -41,12 +142,15 @@ type GenerationResponse struct {
 	Created  string `json:"created_at"`
 }
 
-func (c *OllamaClient) Generate(prompt string, model string) (string, error) {
+// Generate 调用 /api/generate 生成回答，opts 为 nil 时使用默认参数
+func (c *OllamaClient) Generate(prompt string, model string, opts *Options) (string, error) {
+	opts = orDefault(opts)
 	reqBody := GenerationRequest{
-		Model:   model,
-		Prompt:  prompt,
-		Stream:  false,
-		Options: Options{Temperature: 0.7, TopP: 0.9},
+		Model:     model,
+		Prompt:    prompt,
+		Stream:    false,
+		Options:   opts,
+		KeepAlive: opts.KeepAlive,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -77,6 +181,75 @@ func (c *OllamaClient) Generate(prompt string, model string) (string, error) {
 	return result.Response, nil
 }
 
+// 对话消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message 对话中的一条消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatRequest struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Stream    bool      `json:"stream"`
+	Options   *Options  `json:"options,omitempty"`
+	KeepAlive string    `json:"keep_alive,omitempty"`
+}
+
+type ChatResponse struct {
+	Model   string  `json:"model"`
+	Created string  `json:"created_at"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+}
+
+// Chat 调用 /api/chat 接口进行多轮对话，messages 按时间顺序包含 system/user/assistant 消息
+func (c *OllamaClient) Chat(ctx context.Context, model string, messages []Message, opts *Options) (string, error) {
+	opts = orDefault(opts)
+	reqBody := ChatRequest{
+		Model:     model,
+		Messages:  messages,
+		Stream:    false,
+		Options:   opts,
+		KeepAlive: opts.KeepAlive,
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/chat", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
+
+	var result ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	return result.Message.Content, nil
+}
+
 func (c *OllamaClient) ListLocalModels() ([]string, error) {
 	resp, err := c.HTTPClient.Get(c.BaseURL + "/api/tags")
 	if err != nil {
@@ -101,43 +274,41 @@ func (c *OllamaClient) ListLocalModels() ([]string, error) {
 	return models, nil
 }
 
-func (c *OllamaClient) GenerateStream(prompt, model string, ch chan<- string) error {
-	reqBody := GenerationRequest{
-		Model:   model,
-		Prompt:  prompt,
-		Stream:  true,
-		Options: Options{Temperature: 0.7},
+// Embed 调用 /api/embed 为一批文本生成向量
+func (c *OllamaClient) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	jsonBody, _ := json.Marshal(reqBody)
-	resp, err := c.HTTPClient.Post(c.BaseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/embed", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	go func() {
-		defer resp.Body.Close()
-		decoder := json.NewDecoder(resp.Body)
-
-		for {
-			var streamResp struct {
-				Response string `json:"response"`
-				Done     bool   `json:"done"`
-			}
-
-			if err := decoder.Decode(&streamResp); err != nil {
-				close(ch)
-				return
-			}
-
-			if streamResp.Done {
-				close(ch)
-				return
-			}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API请求失败: %v", err)
+	}
+	defer resp.Body.Close()
 
-			ch <- streamResp.Response
-		}
-	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
 
-	return nil
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(result.Embeddings) != len(input) {
+		return nil, fmt.Errorf("向量数量不匹配: 期望 %d，实际 %d", len(input), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
 }
@@ -0,0 +1,196 @@
+package ai_model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// PullProgress 拉取模型时 /api/pull 返回的进度
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+}
+
+// Fraction 当前分层的下载比例，无大小信息时返回 -1
+func (p PullProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// ModelInfo /api/show 返回的模型详情
+type ModelInfo struct {
+	Name          string
+	Modelfile     string
+	Parameters    string
+	Template      string
+	Format        string
+	Family        string
+	ParameterSize string
+	Quantization  string
+	ContextLength int
+}
+
+// doJSON 发送 JSON 请求并检查状态码，out 为 nil 时丢弃响应内容
+func (c *OllamaClient) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("API请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API返回错误: %s (%d)", string(respBody), resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	return nil
+}
+
+// PullModel 通过 /api/pull 下载模型，每收到一条进度调用一次 progress。
+// 下载耗时较长，使用不设超时的 StreamClient，通过 ctx 取消。
+func (c *OllamaClient) PullModel(ctx context.Context, name string, progress func(PullProgress)) error {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model":  name,
+		"stream": true,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/pull", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.StreamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("API请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var p struct {
+			PullProgress
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&p); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				// 收到 success 前连接就结束了，下载没有完成
+				return fmt.Errorf("下载模型 %s 未完成: %w", name, io.ErrUnexpectedEOF)
+			}
+			return fmt.Errorf("解析下载进度失败: %v", err)
+		}
+		if p.Error != "" {
+			return fmt.Errorf("下载模型失败: %s", p.Error)
+		}
+		if progress != nil {
+			progress(p.PullProgress)
+		}
+		if p.Status == "success" {
+			return nil
+		}
+	}
+}
+
+// DeleteModel 删除本地模型
+func (c *OllamaClient) DeleteModel(name string) error {
+	return c.doJSON(context.Background(), http.MethodDelete, "/api/delete", map[string]string{"model": name}, nil)
+}
+
+// CopyModel 复制模型，常用于给模型起别名
+func (c *OllamaClient) CopyModel(source, destination string) error {
+	return c.doJSON(context.Background(), http.MethodPost, "/api/copy", map[string]string{
+		"source":      source,
+		"destination": destination,
+	}, nil)
+}
+
+// ShowModel 获取模型的参数、模板、上下文长度和量化方式
+func (c *OllamaClient) ShowModel(name string) (*ModelInfo, error) {
+	var result struct {
+		Modelfile  string `json:"modelfile"`
+		Parameters string `json:"parameters"`
+		Template   string `json:"template"`
+		Details    struct {
+			Format            string `json:"format"`
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+		ModelInfo map[string]interface{} `json:"model_info"`
+	}
+	if err := c.doJSON(context.Background(), http.MethodPost, "/api/show", map[string]string{"model": name}, &result); err != nil {
+		return nil, err
+	}
+
+	info := &ModelInfo{
+		Name:          name,
+		Modelfile:     result.Modelfile,
+		Parameters:    result.Parameters,
+		Template:      result.Template,
+		Format:        result.Details.Format,
+		Family:        result.Details.Family,
+		ParameterSize: result.Details.ParameterSize,
+		Quantization:  result.Details.QuantizationLevel,
+	}
+	// 上下文长度的键名带有模型架构前缀，如 llama.context_length、qwen2.context_length
+	for k, v := range result.ModelInfo {
+		if strings.HasSuffix(k, ".context_length") {
+			if n, ok := v.(float64); ok {
+				info.ContextLength = int(n)
+			}
+		}
+	}
+	return info, nil
+}
+
+// ContextLength 模型训练时的上下文长度，按模型缓存 ShowModel 的结果；查询失败时返回 0，下次重新查询
+func (c *OllamaClient) ContextLength(model string) int {
+	if n, ok := c.contextLengths.Load(model); ok {
+		return n.(int)
+	}
+	info, err := c.ShowModel(model)
+	if err != nil {
+		log.Printf("获取模型 %s 的上下文长度失败: %v", model, err)
+		return 0
+	}
+	c.contextLengths.Store(model, info.ContextLength)
+	return info.ContextLength
+}
@@ -0,0 +1,317 @@
+package ai_model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAIClient 访问 OpenAI 兼容接口（/v1/chat/completions），
+// 适用于 llama.cpp server、vLLM、LM Studio 等服务
+type OpenAIClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	// StreamClient 用于流式生成，不设置整体超时，由调用方通过 context 控制取消
+	StreamClient *http.Client
+	// MaxContext 配置的上下文长度，接口本身不提供该信息，0 表示未知
+	MaxContext int
+}
+
+// NewOpenAIClient baseURL 形如 http://127.0.0.1:8080，末尾的 /v1 可省略
+func NewOpenAIClient(baseURL, apiKey string) *OpenAIClient {
+	baseURL = strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	return &OpenAIClient{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		StreamClient: &http.Client{},
+	}
+}
+
+type openAIChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []Message            `json:"messages"`
+	Stream        bool                 `json:"stream"`
+	Temperature   *float32             `json:"temperature,omitempty"`
+	TopP          *float32             `json:"top_p,omitempty"`
+	TopK          *int                 `json:"top_k,omitempty"`
+	MaxTokens     *int                 `json:"max_tokens,omitempty"`
+	Seed          *int                 `json:"seed,omitempty"`
+	Stop          []string             `json:"stop,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+// newOpenAIChatRequest 将通用参数映射为 OpenAI 兼容参数，接口不支持的参数（如 mirostat）被忽略
+func newOpenAIChatRequest(model string, messages []Message, opts *Options) openAIChatRequest {
+	opts = orDefault(opts)
+	return openAIChatRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		TopK:        opts.TopK,
+		MaxTokens:   opts.NumPredict,
+		Seed:        opts.Seed,
+		Stop:        opts.Stop,
+	}
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      Message `json:"message"`
+		Delta        Message `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *OpenAIClient) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %v", err)
+		}
+		reader = bytes.NewBuffer(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+func (c *OpenAIClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func (c *OpenAIClient) Generate(prompt string, model string, opts *Options) (string, error) {
+	return c.Chat(context.Background(), model, []Message{{Role: RoleUser, Content: prompt}}, opts)
+}
+
+func (c *OpenAIClient) GenerateStream(ctx context.Context, prompt, model string, opts *Options) (<-chan StreamChunk, error) {
+	return c.ChatStream(ctx, model, []Message{{Role: RoleUser, Content: prompt}}, opts)
+}
+
+func (c *OpenAIClient) Chat(ctx context.Context, model string, messages []Message, opts *Options) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/v1/chat/completions", newOpenAIChatRequest(model, messages, opts))
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("API返回错误: %s", result.Error.Message)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("API未返回结果")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// ChatStream 以 SSE 方式读取流式结果，通道语义与 OllamaClient.GenerateStream 相同
+func (c *OpenAIClient) ChatStream(ctx context.Context, model string, messages []Message, opts *Options) (<-chan StreamChunk, error) {
+	reqBody := newOpenAIChatRequest(model, messages, opts)
+	reqBody.Stream = true
+	reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	req, err := c.newRequest(ctx, http.MethodPost, "/v1/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.do(c.StreamClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		stats := &GenerationStats{}
+		finished := false
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				finished = true
+				break
+			}
+
+			var streamResp openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
+				send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("解析流式响应失败: %v", err)})
+				return
+			}
+			if streamResp.Error != nil {
+				send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("API返回错误: %s", streamResp.Error.Message)})
+				return
+			}
+			if streamResp.Usage != nil {
+				stats.PromptEvalCount = streamResp.Usage.PromptTokens
+				stats.EvalCount = streamResp.Usage.CompletionTokens
+			}
+			for _, choice := range streamResp.Choices {
+				if choice.Delta.Content != "" {
+					if !send(StreamChunk{Type: ChunkText, Text: choice.Delta.Content}) {
+						return
+					}
+				}
+				if choice.FinishReason != nil {
+					finished = true
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err := scanner.Err(); err != nil {
+			send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("读取流式响应失败: %v", err)})
+			return
+		}
+		if !finished {
+			send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("读取流式响应失败: %v", io.ErrUnexpectedEOF)})
+			return
+		}
+
+		stats.TotalDuration = time.Since(start)
+		stats.EvalDuration = stats.TotalDuration
+		send(StreamChunk{Type: ChunkDone, Stats: stats})
+	}()
+
+	return ch, nil
+}
+
+// ContextLength 返回提供方配置中的上下文长度
+func (c *OpenAIClient) ContextLength(model string) int {
+	return c.MaxContext
+}
+
+func (c *OpenAIClient) ListLocalModels() ([]string, error) {
+	req, err := c.newRequest(context.Background(), http.MethodGet, "/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	var models []string
+	for _, m := range response.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// Embed 调用 /v1/embeddings 为一批文本生成向量
+func (c *OpenAIClient) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/v1/embeddings", map[string]interface{}{
+		"model": model,
+		"input": input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(result.Data) != len(input) {
+		return nil, fmt.Errorf("向量数量不匹配: 期望 %d，实际 %d", len(input), len(result.Data))
+	}
+
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	embeddings := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		embeddings[i] = d.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,169 @@
+package ai_model
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+// Provider 大模型服务提供方，Ollama 与 OpenAI 兼容接口均实现该接口。
+// 生成类方法的 opts 为 nil 时使用 DefaultOptions。
+type Provider interface {
+	Generate(prompt string, model string, opts *Options) (string, error)
+	GenerateStream(ctx context.Context, prompt, model string, opts *Options) (<-chan StreamChunk, error)
+	Chat(ctx context.Context, model string, messages []Message, opts *Options) (string, error)
+	ChatStream(ctx context.Context, model string, messages []Message, opts *Options) (<-chan StreamChunk, error)
+	ListLocalModels() ([]string, error)
+	Embed(ctx context.Context, model string, input []string) ([][]float32, error)
+}
+
+// NewProvider 根据提供方配置创建客户端
+func NewProvider(pc config.ProviderConfig) (Provider, error) {
+	switch pc.Type {
+	case config.ProviderOllama, "":
+		return NewOllamaClient(pc.BaseURL), nil
+	case config.ProviderOpenAI:
+		c := NewOpenAIClient(pc.BaseURL, pc.APIKey)
+		c.MaxContext = pc.ContextLength
+		return c, nil
+	default:
+		return nil, fmt.Errorf("不支持的提供方类型: %s", pc.Type)
+	}
+}
+
+// Router 按模型名称把请求分发到对应的提供方
+type Router struct {
+	providers   map[string]Provider
+	order       []string
+	defaultName string
+
+	mu sync.RWMutex
+	// 模型名称 -> 提供方名称，配置项优先，其余由 ListLocalModels 自动发现
+	modelProviders map[string]string
+}
+
+// NewRouter 根据配置创建所有提供方，未配置 ollama 时使用 OllamaURL 作为默认提供方
+func NewRouter(conf *config.AppConfig) (*Router, error) {
+	r := &Router{
+		providers:      make(map[string]Provider),
+		modelProviders: make(map[string]string),
+		defaultName:    config.ProviderOllama,
+	}
+
+	for _, pc := range conf.Providers {
+		if pc.Name == "" {
+			return nil, fmt.Errorf("提供方名称不能为空")
+		}
+		if _, ok := r.providers[pc.Name]; ok {
+			return nil, fmt.Errorf("提供方名称重复: %s", pc.Name)
+		}
+		p, err := NewProvider(pc)
+		if err != nil {
+			return nil, err
+		}
+		r.providers[pc.Name] = p
+		r.order = append(r.order, pc.Name)
+	}
+
+	if _, ok := r.providers[config.ProviderOllama]; !ok {
+		r.providers[config.ProviderOllama] = NewOllamaClient(conf.OllamaURL)
+		r.order = append([]string{config.ProviderOllama}, r.order...)
+	}
+
+	for model, name := range conf.ModelProviders {
+		if _, ok := r.providers[name]; !ok {
+			return nil, fmt.Errorf("模型 %s 指定的提供方不存在: %s", model, name)
+		}
+		r.modelProviders[model] = name
+	}
+	return r, nil
+}
+
+// Provider 按名称获取提供方
+func (r *Router) Provider(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+func (r *Router) providerFor(model string) Provider {
+	r.mu.RLock()
+	name, ok := r.modelProviders[model]
+	r.mu.RUnlock()
+	if !ok {
+		name = r.defaultName
+	}
+	return r.providers[name]
+}
+
+func (r *Router) Generate(prompt string, model string, opts *Options) (string, error) {
+	return r.providerFor(model).Generate(prompt, model, opts)
+}
+
+func (r *Router) GenerateStream(ctx context.Context, prompt, model string, opts *Options) (<-chan StreamChunk, error) {
+	return r.providerFor(model).GenerateStream(ctx, prompt, model, opts)
+}
+
+func (r *Router) Chat(ctx context.Context, model string, messages []Message, opts *Options) (string, error) {
+	return r.providerFor(model).Chat(ctx, model, messages, opts)
+}
+
+func (r *Router) ChatStream(ctx context.Context, model string, messages []Message, opts *Options) (<-chan StreamChunk, error) {
+	return r.providerFor(model).ChatStream(ctx, model, messages, opts)
+}
+
+func (r *Router) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	return r.providerFor(model).Embed(ctx, model, input)
+}
+
+// ContextLength 模型所属提供方报告的上下文长度
+func (r *Router) ContextLength(model string) int {
+	return ContextLength(r.providerFor(model), model)
+}
+
+// ContextLength 模型的上下文长度（token 数），提供方不支持查询或查询失败时返回 0
+func ContextLength(p Provider, model string) int {
+	if c, ok := p.(interface{ ContextLength(model string) int }); ok {
+		return c.ContextLength(model)
+	}
+	return 0
+}
+
+// ListLocalModels 汇总所有提供方的模型，并记录模型所属的提供方。
+// 单个提供方不可用时只记录日志，全部失败才返回错误。
+func (r *Router) ListLocalModels() ([]string, error) {
+	var models []string
+	var lastErr error
+	seen := make(map[string]string)
+
+	for _, name := range r.order {
+		list, err := r.providers[name].ListLocalModels()
+		if err != nil {
+			log.Printf("获取提供方 %s 的模型列表失败: %v", name, err)
+			lastErr = err
+			continue
+		}
+		for _, m := range list {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = name
+			models = append(models, m)
+		}
+	}
+
+	r.mu.Lock()
+	for m, name := range seen {
+		if _, ok := r.modelProviders[m]; !ok {
+			r.modelProviders[m] = name
+		}
+	}
+	r.mu.Unlock()
+
+	if len(models) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return models, nil
+}
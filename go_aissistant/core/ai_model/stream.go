@@ -0,0 +1,179 @@
+package ai_model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ChunkType 流式输出片段的类型
+type ChunkType int
+
+const (
+	ChunkText  ChunkType = iota // 文本增量
+	ChunkDone                   // 生成结束，携带统计信息
+	ChunkError                  // 生成出错，携带错误
+)
+
+// GenerationStats 生成结束时 Ollama 返回的统计信息
+type GenerationStats struct {
+	TotalDuration      time.Duration
+	LoadDuration       time.Duration
+	PromptEvalCount    int
+	PromptEvalDuration time.Duration
+	EvalCount          int
+	EvalDuration       time.Duration
+}
+
+// TokensPerSecond 计算输出速度
+func (s *GenerationStats) TokensPerSecond() float64 {
+	if s == nil || s.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(s.EvalCount) / s.EvalDuration.Seconds()
+}
+
+// StreamChunk 流式输出中的一个片段
+type StreamChunk struct {
+	Type  ChunkType
+	Text  string
+	Stats *GenerationStats
+	Err   error
+}
+
+// ollamaStreamResponse 同时兼容 /api/generate 与 /api/chat 的流式响应
+type ollamaStreamResponse struct {
+	Response           string   `json:"response"`
+	Message            *Message `json:"message"`
+	Done               bool     `json:"done"`
+	Error              string   `json:"error"`
+	TotalDuration      int64    `json:"total_duration"`
+	LoadDuration       int64    `json:"load_duration"`
+	PromptEvalCount    int      `json:"prompt_eval_count"`
+	PromptEvalDuration int64    `json:"prompt_eval_duration"`
+	EvalCount          int      `json:"eval_count"`
+	EvalDuration       int64    `json:"eval_duration"`
+}
+
+func (r *ollamaStreamResponse) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
+
+func (r *ollamaStreamResponse) stats() *GenerationStats {
+	return &GenerationStats{
+		TotalDuration:      time.Duration(r.TotalDuration),
+		LoadDuration:       time.Duration(r.LoadDuration),
+		PromptEvalCount:    r.PromptEvalCount,
+		PromptEvalDuration: time.Duration(r.PromptEvalDuration),
+		EvalCount:          r.EvalCount,
+		EvalDuration:       time.Duration(r.EvalDuration),
+	}
+}
+
+// GenerateStream 以流式方式调用 /api/generate。
+// 返回的通道依次输出文本片段，最后以 ChunkDone 或 ChunkError 结束并关闭；
+// ctx 取消后请求被中断，通道直接关闭。
+func (c *OllamaClient) GenerateStream(ctx context.Context, prompt, model string, opts *Options) (<-chan StreamChunk, error) {
+	opts = orDefault(opts)
+	reqBody := GenerationRequest{
+		Model:     model,
+		Prompt:    prompt,
+		Stream:    true,
+		Options:   opts,
+		KeepAlive: opts.KeepAlive,
+	}
+	return c.stream(ctx, "/api/generate", reqBody)
+}
+
+// ChatStream 以流式方式调用 /api/chat，通道语义与 GenerateStream 相同
+func (c *OllamaClient) ChatStream(ctx context.Context, model string, messages []Message, opts *Options) (<-chan StreamChunk, error) {
+	opts = orDefault(opts)
+	reqBody := ChatRequest{
+		Model:     model,
+		Messages:  messages,
+		Stream:    true,
+		Options:   opts,
+		KeepAlive: opts.KeepAlive,
+	}
+	return c.stream(ctx, "/api/chat", reqBody)
+}
+
+func (c *OllamaClient) stream(ctx context.Context, path string, reqBody interface{}) (<-chan StreamChunk, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.StreamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API请求失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var streamResp ollamaStreamResponse
+			if err := decoder.Decode(&streamResp); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("解析流式响应失败: %v", err)})
+				return
+			}
+
+			if streamResp.Error != "" {
+				send(StreamChunk{Type: ChunkError, Err: fmt.Errorf("API返回错误: %s", streamResp.Error)})
+				return
+			}
+
+			if text := streamResp.text(); text != "" {
+				if !send(StreamChunk{Type: ChunkText, Text: text}) {
+					return
+				}
+			}
+
+			if streamResp.Done {
+				send(StreamChunk{Type: ChunkDone, Stats: streamResp.stats()})
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
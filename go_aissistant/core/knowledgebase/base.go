@@ -1,66 +1,410 @@
 package knowledgebase
 
-import "github.com/fighthorse/aicode/go_aissistant/config"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
 
 type KnowledgeBaseI interface {
+	// Name 集合名称
+	Name() string
 	Initialize() error
+	// AddDocuments 写入文档，ID 相同的文档会被覆盖
 	AddDocuments(docs []Document) error
-	Query(query string, nResults int) ([]Document, error)
+	// Query 检索与 query 最相近的文档，按相似度从高到低排列，结果的 Score 为相似度
+	Query(query string, opts QueryOptions) ([]Document, error)
 	DeleteDocument(id string) error
+	// DeleteBySource 删除从指定文件导入的全部块，即元数据 path 等于该路径的文档，ID 在 keep 中的除外；没有匹配时不报错
+	DeleteBySource(path string, keep ...string) error
 	ListDocuments() ([]Document, error)
+	// ReEmbed 使用当前配置的向量模型重新生成全部文档的向量
+	ReEmbed() error
 }
 
 type Document struct {
 	ID       string                 `json:"id"`
 	Text     string                 `json:"text"`
 	Metadata map[string]interface{} `json:"metadata"`
+	// Score 检索结果的相关度，越大越相近；向量检索时为余弦相似度（1-Score 即余弦距离），
+	// 关键词检索时为相对于最高分的 BM25 得分，混合检索或合并多个查询的结果时为归一化的融合得分，均在 0-1 之间；非检索结果为 0
+	Score float32 `json:"score,omitempty"`
+	// RerankScore 重排得分 0-1，未经重排时为 0
+	RerankScore float32 `json:"rerank_score,omitempty"`
+}
+
+// Page 块所在的页码，不是分页文档时为 0
+func (d Document) Page() int {
+	return int(metaInt(d.Metadata[MetaPage]))
+}
+
+// MetaCollection 跨集合检索时，结果元数据中记录来源集合
+const MetaCollection = "collection"
+
+// ErrCollectionNotFound 集合不存在，可能已被删除或重命名
+var ErrCollectionNotFound = errors.New("集合不存在")
+
+// 集合名称需同时满足 Chroma 和 Milvus 的命名规则
+var collectionNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,62}$`)
+
+// collectionStore 各向量库后端的集合管理
+type collectionStore interface {
+	// open 返回指定集合的知识库，尚未初始化
+	open(name string) (KnowledgeBaseI, error)
+	list() ([]string, error)
+	rename(oldName, newName string) error
+	drop(name string) error
 }
 
+// KnowledgeBaseManager 管理多个命名集合，未指定集合的操作作用于配置中的 collection_name
 type KnowledgeBaseManager struct {
-	config    *config.AppConfig
-	defaultKb KnowledgeBaseI
+	config   *config.AppConfig
+	store    collectionStore
+	versions *VersionStore
+
+	mu    sync.Mutex
+	bases map[string]KnowledgeBaseI
+	// adminMu 串行执行新建、重命名和删除，使检查名称和修改集合之间不被其他管理操作打断
+	adminMu sync.Mutex
 }
 
 func NewKnowledgeBaseManager(conf *config.AppConfig) (*KnowledgeBaseManager, error) {
+	// 根据配置初始化向量库
+	store, err := newCollectionStore(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := NewVersionStore(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	manager := &KnowledgeBaseManager{
-		config: conf,
+		config:   conf,
+		store:    store,
+		versions: versions,
+		bases:    make(map[string]KnowledgeBaseI),
+	}
+
+	// 初始化默认知识库
+	if _, err := manager.open(conf.CollectionName, true); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// newCollectionStore 按 vector_store 配置创建向量库，默认使用内置的本地向量库
+func newCollectionStore(conf *config.AppConfig) (collectionStore, error) {
+	if conf.UseMilvus == "1" {
+		return newMilvusStore(conf)
 	}
 
-	// 根据配置初始化默认知识库
+	switch conf.VectorStore {
+	case config.VectorStoreMilvus:
+		return newMilvusStore(conf)
+	case config.VectorStoreChroma:
+		return newChromaStore(conf)
+	case config.VectorStoreLocal, "":
+		return newLocalStore(conf)
+	default:
+		return nil, fmt.Errorf("不支持的向量库类型: %s", conf.VectorStore)
+	}
+}
+
+// DefaultCollection 配置中的默认集合名称
+func (km *KnowledgeBaseManager) DefaultCollection() string {
+	return km.config.CollectionName
+}
 
-	chromaDb, err := NewChromaKB(conf)
+// Versions 各集合中来源文件的导入历史
+func (km *KnowledgeBaseManager) Versions() *VersionStore {
+	return km.versions
+}
+
+// Importer 按配置创建导入器，导入时记录版本历史
+func (km *KnowledgeBaseManager) Importer() *Importer {
+	importer := NewImporterFromConfig(km.config)
+	importer.Versions = km.versions
+	return importer
+}
+
+// Collection 返回已存在集合的知识库，首次访问时初始化；集合不存在时返回 ErrCollectionNotFound，不会创建。
+// 向量模型不一致时仍返回知识库，以便执行重新向量化。
+func (km *KnowledgeBaseManager) Collection(name string) (KnowledgeBaseI, error) {
+	return km.open(name, false)
+}
+
+// EnsureCollection 返回指定集合的知识库，集合不存在时按名称规则校验后创建，用于导入和监听文件夹
+func (km *KnowledgeBaseManager) EnsureCollection(name string) (KnowledgeBaseI, error) {
+	if kb, err := km.Collection(name); !errors.Is(err, ErrCollectionNotFound) {
+		return kb, err
+	}
+	if err := km.CreateCollection(name); err != nil && !errors.Is(err, errCollectionExists) {
+		return nil, err
+	}
+	return km.Collection(name)
+}
+
+// open 取缓存的知识库，没有时打开并初始化。初始化需要访问向量库和向量模型，在锁外进行，
+// 避免一个响应慢的集合阻塞其他集合；create 为 false 时集合必须已存在
+func (km *KnowledgeBaseManager) open(name string, create bool) (KnowledgeBaseI, error) {
+	km.mu.Lock()
+	kb, ok := km.bases[name]
+	km.mu.Unlock()
+	if ok {
+		return kb, nil
+	}
+
+	if !create {
+		exists, err := km.exists(name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+		}
+	}
+
+	kb, err := km.store.open(name)
 	if err != nil {
 		return nil, err
 	}
-	manager.defaultKb = chromaDb
+	if err := kb.Initialize(); err != nil {
+		if !errors.Is(err, ErrEmbeddingMismatch) {
+			return nil, err
+		}
+		log.Printf("知识库需要重新向量化: %v", err)
+	}
 
-	if err = manager.defaultKb.Initialize(); err != nil {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	// 同时打开同一集合时使用先完成的一个
+	if existing, ok := km.bases[name]; ok {
+		return existing, nil
+	}
+	kb = newHybridKB(kb, km.config)
+	km.bases[name] = kb
+	return kb, nil
+}
+
+// Collections 列出向量库中的全部集合
+func (km *KnowledgeBaseManager) Collections() ([]string, error) {
+	names, err := km.store.list()
+	if err != nil {
 		return nil, err
 	}
+	sort.Strings(names)
+	return names, nil
+}
 
-	return manager, nil
+func (km *KnowledgeBaseManager) exists(name string) (bool, error) {
+	names, err := km.store.list()
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var errCollectionExists = errors.New("集合已存在")
+
+// CreateCollection 新建集合，名称须以字母开头，由字母、数字和下划线组成，长度 3-63
+func (km *KnowledgeBaseManager) CreateCollection(name string) error {
+	if !collectionNamePattern.MatchString(name) {
+		return fmt.Errorf("集合名称无效: %s（须以字母开头，由字母、数字和下划线组成，长度 3-63）", name)
+	}
+
+	km.adminMu.Lock()
+	defer km.adminMu.Unlock()
+	exists, err := km.exists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", errCollectionExists, name)
+	}
+	_, err = km.open(name, true)
+	return err
+}
+
+// RenameCollection 重命名集合，默认集合需在配置中修改
+func (km *KnowledgeBaseManager) RenameCollection(oldName, newName string) error {
+	if oldName == km.config.CollectionName {
+		return fmt.Errorf("默认集合 %s 不能重命名，请修改配置中的 collection_name", oldName)
+	}
+	if !collectionNamePattern.MatchString(newName) {
+		return fmt.Errorf("集合名称无效: %s（须以字母开头，由字母、数字和下划线组成，长度 3-63）", newName)
+	}
+
+	km.adminMu.Lock()
+	defer km.adminMu.Unlock()
+	exists, err := km.exists(newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", errCollectionExists, newName)
+	}
+	if err := km.store.rename(oldName, newName); err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	delete(km.bases, oldName)
+	km.mu.Unlock()
+	return km.versions.renameCollection(oldName, newName)
+}
+
+// DropCollection 删除集合及其全部文档，默认集合不能删除
+func (km *KnowledgeBaseManager) DropCollection(name string) error {
+	if name == km.config.CollectionName {
+		return fmt.Errorf("默认集合 %s 不能删除", name)
+	}
+
+	km.adminMu.Lock()
+	defer km.adminMu.Unlock()
+	if err := km.store.drop(name); err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	delete(km.bases, name)
+	km.mu.Unlock()
+	return km.versions.dropCollection(name)
+}
+
+// QueryCollections 在 opts.Collections 指定的集合中检索（为空时检索默认集合），各集合结果按名次交替合并，
+// 结果元数据中的 collection 记录来源集合
+func (km *KnowledgeBaseManager) QueryCollections(query string, opts QueryOptions) ([]Document, error) {
+	names := opts.Collections
+	if len(names) == 0 {
+		names = []string{km.config.CollectionName}
+	}
+	numResults := opts.NumResults
+
+	var results [][]Document
+	var lastErr error
+	for _, name := range names {
+		kb, err := km.Collection(name)
+		if err != nil {
+			log.Printf("打开集合 %s 失败: %v", name, err)
+			lastErr = err
+			continue
+		}
+		docs, err := kb.Query(query, opts)
+		if err != nil {
+			log.Printf("查询集合 %s 失败: %v", name, err)
+			lastErr = err
+			continue
+		}
+		// 后端返回的元数据可能与其内部索引共用，复制后再写入集合名
+		for i := range docs {
+			metadata := make(map[string]interface{}, len(docs[i].Metadata)+1)
+			for k, v := range docs[i].Metadata {
+				metadata[k] = v
+			}
+			metadata[MetaCollection] = name
+			docs[i].Metadata = metadata
+		}
+		results = append(results, docs)
+	}
+	if len(results) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	var merged []Document
+	for rank := 0; len(merged) < numResults; rank++ {
+		added := false
+		for _, docs := range results {
+			if rank < len(docs) && len(merged) < numResults {
+				merged = append(merged, docs[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return merged, nil
+}
+
+func (km *KnowledgeBaseManager) defaultKb() (KnowledgeBaseI, error) {
+	return km.open(km.config.CollectionName, true)
+}
+
+// 默认集合名称
+func (km *KnowledgeBaseManager) Name() string {
+	return km.config.CollectionName
 }
 
 func (km *KnowledgeBaseManager) Initialize() error {
-	return km.defaultKb.Initialize()
+	kb, err := km.defaultKb()
+	if err != nil {
+		return err
+	}
+	return kb.Initialize()
 }
 
 // 添加文档到知识库
 func (km *KnowledgeBaseManager) AddDocuments(docs []Document) error {
-	return km.defaultKb.AddDocuments(docs)
+	kb, err := km.defaultKb()
+	if err != nil {
+		return err
+	}
+	return kb.AddDocuments(docs)
 }
 
 // 查询知识库
-func (km *KnowledgeBaseManager) Query(query string, numResults int) ([]Document, error) {
-	return km.defaultKb.Query(query, numResults)
+func (km *KnowledgeBaseManager) Query(query string, opts QueryOptions) ([]Document, error) {
+	kb, err := km.defaultKb()
+	if err != nil {
+		return nil, err
+	}
+	return kb.Query(query, opts)
 }
 
 // 删除文档
 func (km *KnowledgeBaseManager) DeleteDocument(id string) error {
-	return km.defaultKb.DeleteDocument(id)
+	kb, err := km.defaultKb()
+	if err != nil {
+		return err
+	}
+	return kb.DeleteDocument(id)
+}
+
+// 删除从指定文件导入的全部块
+func (km *KnowledgeBaseManager) DeleteBySource(path string) error {
+	kb, err := km.defaultKb()
+	if err != nil {
+		return err
+	}
+	return kb.DeleteBySource(path)
 }
 
 // 列出所有文档
 func (km *KnowledgeBaseManager) ListDocuments() ([]Document, error) {
-	return km.defaultKb.ListDocuments()
+	kb, err := km.defaultKb()
+	if err != nil {
+		return nil, err
+	}
+	return kb.ListDocuments()
+}
+
+// 使用当前向量模型重建知识库
+func (km *KnowledgeBaseManager) ReEmbed() error {
+	kb, err := km.defaultKb()
+	if err != nil {
+		return err
+	}
+	return kb.ReEmbed()
 }
@@ -2,16 +2,33 @@ package knowledgebase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/amikos-tech/chroma-go/pkg/embeddings/ollama"
 	"github.com/fighthorse/aicode/go_aissistant/config"
 	"log"
+	"strings"
 	"sync"
 
 	chroma "github.com/amikos-tech/chroma-go"
 	"github.com/amikos-tech/chroma-go/types"
 )
 
+const (
+	defaultEmbeddingModel     = "nomic-embed-text"
+	defaultEmbeddingBatchSize = 32
+
+	// 重新向量化时临时集合名的后缀
+	reembedSuffix = "__reembed"
+
+	// 集合元数据中记录的向量模型信息，用于检测模型变更
+	metaEmbeddingModel     = "embedding_model"
+	metaEmbeddingDimension = "embedding_dimension"
+)
+
+// ErrEmbeddingMismatch 集合中已有向量与当前配置的向量模型不一致，需要重新向量化
+var ErrEmbeddingMismatch = errors.New("知识库向量模型与配置不一致，请执行重新向量化")
+
 type ChromaKB struct {
 	collectionName string
 	client         *chroma.Client
@@ -19,6 +36,12 @@ type ChromaKB struct {
 	collectionMu   sync.RWMutex
 	embeddingFunc  types.EmbeddingFunction
 	metadata       map[string]interface{}
+
+	embeddingModel     string
+	embeddingDimension int
+	batchSize          int
+	// 初始化时发现的模型不一致错误，重新向量化之前拒绝读写
+	mismatchErr error
 }
 
 // NewKnowledgeBase creates a new KnowledgeBase instance
@@ -33,135 +56,269 @@ func NewChromaKB(config *config.AppConfig) (*ChromaKB, error) {
 		return nil, fmt.Errorf("error creating client: %w", err)
 	}
 
-	embeddingFunc, err := ollama.NewOllamaEmbeddingFunction(ollama.WithBaseURL("http://127.0.0.1:11434"), ollama.WithModel("nomic-embed-text"))
+	embeddingModel := config.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = defaultEmbeddingModel
+	}
+	batchSize := config.EmbeddingBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+
+	embeddingFunc, err := ollama.NewOllamaEmbeddingFunction(ollama.WithBaseURL(config.OllamaURL), ollama.WithModel(embeddingModel))
 	if err != nil {
-		fmt.Printf("Error creating Ollama embedding function: %s \n", err)
+		log.Printf("Error creating Ollama embedding function: %s", err)
+		return nil, fmt.Errorf("创建向量函数失败: %w", err)
 	}
-	metadata := make(map[string]interface{})
-	fmt.Println("NewChromaKB ==>", config.CollectionName)
+	metadata := map[string]interface{}{
+		metaEmbeddingModel: embeddingModel,
+	}
+	fmt.Println("NewChromaKB ==>", config.CollectionName, " 向量模型：", embeddingModel)
 	return &ChromaKB{
-		collectionName: config.CollectionName,
-		client:         client,
-		embeddingFunc:  embeddingFunc,
-		metadata:       metadata,
+		collectionName:     config.CollectionName,
+		client:             client,
+		embeddingFunc:      embeddingFunc,
+		metadata:           metadata,
+		embeddingModel:     embeddingModel,
+		embeddingDimension: config.EmbeddingDimension,
+		batchSize:          batchSize,
 	}, nil
 }
 
-// Initialize initializes the knowledge base with a specific collection
+func (kb *ChromaKB) Name() string {
+	return kb.collectionName
+}
+
+// Initialize initializes the knowledge base with a specific collection.
+// 已存在的集合会校验向量模型和维度，不一致时返回 ErrEmbeddingMismatch，
+// 此时仍可调用 ReEmbed 用新模型重建向量。
 func (kb *ChromaKB) Initialize() error {
 	kb.collectionMu.Lock()
 	defer kb.collectionMu.Unlock()
 	fmt.Println("NewChromaKB Initialize")
 	ctx := context.Background()
-	//var collections []*chroma.Collection
-	//var err error
-	//collections, err = kb.client.ListCollections(ctx)
-	//if err != nil {
-	//	log.Printf("列出集合时出错: %v", err)
-	//}
-	//
-	//if len(collections) > 0 {
-	//	for _, c := range collections {
-	//		if c.Name == kb.collectionName {
-	//			kb.collection = c
-	//			return nil
-	//		}
-	//	}
-	//}
-
-	metadata := map[string]interface{}{} // 使用空 map 或默认值
-	embeddingFunction := types.NewConsistentHashEmbeddingFunction()
-	fmt.Println("CreateCollection =>", kb.collectionName)
+
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing, err := kb.findCollection(ctx)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		kb.collection = existing
+		kb.mismatchErr = kb.checkCollection(ctx, existing, dimension)
+		if kb.mismatchErr != nil {
+			log.Printf("%v", kb.mismatchErr)
+			return kb.mismatchErr
+		}
+		return nil
+	}
+
+	created, err := kb.createCollection(ctx, kb.collectionName, dimension)
+	if err != nil {
+		return err
+	}
+	kb.collection = created
+	kb.mismatchErr = nil
+	return nil
+}
+
+// probeDimension 用当前向量模型生成一次向量得到实际维度，并与配置的维度核对
+func (kb *ChromaKB) probeDimension(ctx context.Context) (int, error) {
+	embedding, err := kb.embeddingFunc.EmbedQuery(ctx, "dimension probe")
+	if err != nil {
+		log.Printf("调用向量模型 %s 失败: %v", kb.embeddingModel, err)
+		return 0, fmt.Errorf("调用向量模型 %s 失败: %w", kb.embeddingModel, err)
+	}
+	dimension := embedding.Len()
+	if kb.embeddingDimension > 0 && kb.embeddingDimension != dimension {
+		return 0, fmt.Errorf("配置的向量维度 %d 与模型 %s 实际维度 %d 不一致", kb.embeddingDimension, kb.embeddingModel, dimension)
+	}
+	return dimension, nil
+}
+
+func (kb *ChromaKB) findCollection(ctx context.Context) (*chroma.Collection, error) {
+	collections, err := kb.client.ListCollections(ctx)
+	if err != nil {
+		log.Printf("列出集合时出错: %v", err)
+		return nil, fmt.Errorf("列出集合时出错: %w", err)
+	}
+	for _, c := range collections {
+		if c.Name == kb.collectionName {
+			c.EmbeddingFunction = kb.embeddingFunc
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkCollection 对比集合元数据中的模型与维度；旧集合没有记录时取一条已有向量检查维度
+func (kb *ChromaKB) checkCollection(ctx context.Context, c *chroma.Collection, dimension int) error {
+	if model, ok := c.Metadata[metaEmbeddingModel].(string); ok && model != kb.embeddingModel {
+		return fmt.Errorf("%w: 集合 %s 使用 %s，当前配置为 %s", ErrEmbeddingMismatch, c.Name, model, kb.embeddingModel)
+	}
+
+	existing := 0
+	switch v := c.Metadata[metaEmbeddingDimension].(type) {
+	case int32:
+		existing = int(v)
+	case float32:
+		existing = int(v)
+	default:
+		results, err := c.GetWithOptions(ctx, types.WithLimit(1), types.WithInclude(types.IEmbeddings))
+		if err != nil {
+			return fmt.Errorf("读取集合向量时出错: %w", err)
+		}
+		if len(results.Embeddings) > 0 && results.Embeddings[0] != nil {
+			existing = results.Embeddings[0].Len()
+		}
+	}
+	if existing > 0 && existing != dimension {
+		return fmt.Errorf("%w: 集合 %s 的向量维度为 %d，当前模型 %s 为 %d", ErrEmbeddingMismatch, c.Name, existing, kb.embeddingModel, dimension)
+	}
+	return nil
+}
+
+func (kb *ChromaKB) createCollection(ctx context.Context, name string, dimension int) (*chroma.Collection, error) {
+	metadata := make(map[string]interface{}, len(kb.metadata)+1)
+	for k, v := range kb.metadata {
+		metadata[k] = v
+	}
+	metadata[metaEmbeddingDimension] = dimension
+
+	fmt.Println("CreateCollection =>", name)
 	newCollection, err := kb.client.CreateCollection(
 		ctx,
-		kb.collectionName,
+		name,
 		metadata,
 		true,
-		embeddingFunction,
-		types.L2,
+		kb.embeddingFunc,
+		types.COSINE,
 	)
 	if err != nil {
-		log.Fatalf("Error creating collection: %s \n", err)
+		log.Printf("Error creating collection: %s", err)
+		return nil, fmt.Errorf("创建集合失败: %w", err)
 	}
-	kb.collection = newCollection
-	return nil
+	return newCollection, nil
+}
+
+func (kb *ChromaKB) ready() error {
+	if kb.collection == nil {
+		log.Printf("集合未初始化")
+		return fmt.Errorf("集合未初始化")
+	}
+	return kb.mismatchErr
 }
 
-// AddDocuments adds documents to the knowledge base
 // AddDocuments adds documents to the knowledge base
 func (kb *ChromaKB) AddDocuments(docs []Document) error {
 	kb.collectionMu.Lock()
 	defer kb.collectionMu.Unlock()
 
-	ctx := context.Background()
-
-	// Create a new record set with to hold the records to insert
-	rs, err := types.NewRecordSet(
-		types.WithEmbeddingFunction(kb.collection.EmbeddingFunction), // we pass the embedding function from the collection
-		types.WithIDGenerator(types.NewULIDGenerator()),
-	)
-	if err != nil {
-		log.Fatalf("Error creating record set: %s \n", err)
-	}
-	for _, doc := range docs {
-		// Add a few records to the record set
-		rs.WithRecord(types.WithDocument(doc.Text), types.WithMetadata("metadata", doc.Metadata))
-	}
-	// Build and validate the record set (this will create embeddings if not already present)
-	_, err = rs.BuildAndValidate(ctx)
-	if err != nil {
-		log.Fatalf("Error validating record set: %s \n", err)
+	if err := kb.ready(); err != nil {
+		return err
 	}
+	return kb.addBatches(context.Background(), kb.collection, docs)
+}
 
-	// Add the records to the collection
-	_, err = kb.collection.AddRecords(context.Background(), rs)
-	if err != nil {
-		log.Fatalf("Error adding documents: %s \n", err)
-	}
+// addBatches 按 EmbeddingBatchSize 分批生成向量并以 upsert 写入 collection，文档没有 ID 时生成 ULID
+func (kb *ChromaKB) addBatches(ctx context.Context, collection *chroma.Collection, docs []Document) error {
+	for start := 0; start < len(docs); start += kb.batchSize {
+		end := start + kb.batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
 
+		// Create a new record set with to hold the records to insert
+		rs, err := types.NewRecordSet(
+			types.WithEmbeddingFunction(kb.embeddingFunc),
+			types.WithIDGenerator(types.NewULIDGenerator()),
+		)
+		if err != nil {
+			log.Printf("Error creating record set: %s", err)
+			return fmt.Errorf("创建记录集失败: %w", err)
+		}
+		for _, doc := range docs[start:end] {
+			rs.WithRecord(types.WithID(doc.ID), types.WithDocument(doc.Text), types.WithMetadatas(chromaMetadata(doc.Metadata)))
+		}
+		// Build and validate the record set (this will create embeddings if not already present)
+		if _, err = rs.BuildAndValidate(ctx); err != nil {
+			log.Printf("Error validating record set: %s", err)
+			return fmt.Errorf("生成向量失败: %w", err)
+		}
+
+		// ID 已存在时覆盖，重复导入同一内容不会产生重复的文档
+		if _, err = collection.Upsert(ctx, rs.GetEmbeddings(), rs.GetMetadatas(), rs.GetDocuments(), rs.GetIDs()); err != nil {
+			log.Printf("Error adding documents: %s", err)
+			return fmt.Errorf("写入文档失败: %w", err)
+		}
+	}
 	return nil
 }
 
-// Query queries the knowledge base for documents
-func (kb *ChromaKB) Query(query string, nResults int) ([]Document, error) {
+// chromaMetadata 把元数据转换为 Chroma 支持的 string、int、float32、bool 类型
+func chromaMetadata(metadata map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		switch val := v.(type) {
+		case string, int, float32, bool:
+			result[k] = val
+		case int32:
+			result[k] = int(val)
+		case int64:
+			result[k] = int(val)
+		case float64:
+			result[k] = float32(val)
+		case nil:
+		default:
+			result[k] = fmt.Sprint(val)
+		}
+	}
+	return result
+}
+
+// Query queries the knowledge base for documents.
+// 来源条件转为 where 子句，其余条件在多取的候选中筛选；集合使用余弦距离，相似度为 1-距离
+func (kb *ChromaKB) Query(query string, opts QueryOptions) ([]Document, error) {
 	kb.collectionMu.RLock()
 	defer kb.collectionMu.RUnlock()
-	if kb.collection == nil {
-		log.Printf("集合未初始化")
-		return nil, fmt.Errorf("集合未初始化")
+	if err := kb.ready(); err != nil {
+		return nil, err
 	}
 	ctx := context.Background()
-	//sl, _ := kb.embeddingFunc.EmbedQuery(ctx, query)
-	//fmt.Println("EmbedQuery:", sl)
-	fmt.Println("Query:", query, "  ", nResults)
-	results, err := kb.collection.Query(ctx, []string{query}, int32(nResults), nil, nil, nil)
+	fmt.Println("Query:", query, "  ", opts.NumResults)
+	var where map[string]interface{}
+	if len(opts.Sources) > 0 {
+		where = map[string]interface{}{"source": map[string]interface{}{"$in": opts.Sources}}
+	}
+	results, err := kb.collection.Query(ctx, []string{query}, int32(opts.fetchSize()), where, nil, nil)
 	if err != nil {
 		log.Printf("查询文档时出错: %v", err)
 		return nil, fmt.Errorf("查询文档时出错: %w", err)
 	}
-	fmt.Println("results:", results)
 
+	// 只有一条查询文本，结果都在下标 0
 	var docs []Document
-	for k, v := range results.Ids {
-		ids := ""
-		if len(v) > 0 {
-			ids = v[0]
+	if len(results.Ids) == 0 {
+		return docs, nil
+	}
+	for i, id := range results.Ids[0] {
+		doc := Document{ID: id}
+		if len(results.Documents) > 0 && i < len(results.Documents[0]) {
+			doc.Text = results.Documents[0][i]
 		}
-		Text := ""
-		if len(results.Documents) < k {
-			Text = results.Documents[k][0]
+		if len(results.Metadatas) > 0 && i < len(results.Metadatas[0]) {
+			doc.Metadata = results.Metadatas[0][i]
 		}
-		var Metadatas map[string]interface{}
-		if len(results.Metadatas) < k {
-			Metadatas = results.Metadatas[k][0]
+		if len(results.Distances) > 0 && i < len(results.Distances[0]) {
+			doc.Score = 1 - results.Distances[0][i]
 		}
-		docs = append(docs, Document{
-			ID:       ids,
-			Text:     Text,
-			Metadata: Metadatas,
-		})
+		docs = append(docs, doc)
 	}
-	return docs, nil
+	return opts.filterDocuments(docs), nil
 }
 
 // DeleteDocument deletes a document from the knowledge base
@@ -169,6 +326,9 @@ func (kb *ChromaKB) DeleteDocument(id string) error {
 	kb.collectionMu.Lock()
 	defer kb.collectionMu.Unlock()
 
+	if kb.collection == nil {
+		return fmt.Errorf("集合未初始化")
+	}
 	ctx := context.Background()
 	deletedIds, err := kb.collection.Delete(ctx, []string{id}, nil, nil)
 	if err != nil {
@@ -183,34 +343,189 @@ func (kb *ChromaKB) DeleteDocument(id string) error {
 	return nil
 }
 
+// DeleteBySource 用 where 条件删除元数据 path 匹配的文档；有要保留的文档时先查出匹配的 ID，再按 ID 删除其余的
+func (kb *ChromaKB) DeleteBySource(path string, keep ...string) error {
+	kb.collectionMu.Lock()
+	defer kb.collectionMu.Unlock()
+
+	if kb.collection == nil {
+		return fmt.Errorf("集合未初始化")
+	}
+	ctx := context.Background()
+	where := map[string]interface{}{MetaPath: path}
+	if len(keep) == 0 {
+		if _, err := kb.collection.Delete(ctx, nil, where, nil); err != nil {
+			log.Printf("删除文档时出错: %v", err)
+			return fmt.Errorf("删除文档时出错: %w", err)
+		}
+		return nil
+	}
+
+	results, err := kb.collection.Get(ctx, where, nil, nil, []types.QueryEnum{})
+	if err != nil {
+		return fmt.Errorf("查询文档失败: %w", err)
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+	var stale []string
+	for _, id := range results.Ids {
+		if !kept[id] {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	if _, err := kb.collection.Delete(ctx, stale, nil, nil); err != nil {
+		log.Printf("删除文档时出错: %v", err)
+		return fmt.Errorf("删除文档时出错: %w", err)
+	}
+	return nil
+}
+
 // ListDocuments lists all documents in the knowledge base
 func (kb *ChromaKB) ListDocuments() ([]Document, error) {
 	kb.collectionMu.RLock()
 	defer kb.collectionMu.RUnlock()
 
-	ctx := context.Background()
-	results, err := kb.collection.Get(ctx, nil, nil, nil, nil) // 假设 Get 方法用于列出文档，具体方法名需根据库文档确认
+	if kb.collection == nil {
+		return nil, fmt.Errorf("集合未初始化")
+	}
+	return kb.listAll(context.Background())
+}
+
+func (kb *ChromaKB) listAll(ctx context.Context) ([]Document, error) {
+	results, err := kb.collection.Get(ctx, nil, nil, nil, nil)
 	if err != nil {
 		log.Printf("列出文档时出错: %v", err)
 		return nil, fmt.Errorf("列出文档时出错: %w", err)
 	}
 
 	var docs []Document
-	for k, v := range results.Ids {
-		ids := v
-		Text := ""
-		if len(results.Documents) < k {
-			Text = results.Documents[k]
-		}
-		var Metadatas map[string]interface{}
-		if len(results.Metadatas) < k {
-			Metadatas = results.Metadatas[k]
-		}
-		docs = append(docs, Document{
-			ID:       ids,
-			Text:     Text,
-			Metadata: Metadatas,
-		})
+	for i, id := range results.Ids {
+		doc := Document{ID: id}
+		if i < len(results.Documents) {
+			doc.Text = results.Documents[i]
+		}
+		if i < len(results.Metadatas) {
+			doc.Metadata = results.Metadatas[i]
+		}
+		docs = append(docs, doc)
 	}
 	return docs, nil
 }
+
+// ReEmbed 向量模型变更后，读出全部文档，用当前模型写入临时集合，全部写入成功后再删除旧集合并把临时集合改为原名，
+// 文档 ID 保持不变。向量化或写入失败时旧集合不受影响
+func (kb *ChromaKB) ReEmbed() error {
+	kb.collectionMu.Lock()
+	defer kb.collectionMu.Unlock()
+	ctx := context.Background()
+
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	var docs []Document
+	if kb.collection != nil {
+		if docs, err = kb.listAll(ctx); err != nil {
+			return err
+		}
+	}
+
+	// 上次中断留下的临时集合
+	tmpName := kb.collectionName + reembedSuffix
+	if _, err := kb.client.DeleteCollection(ctx, tmpName); err == nil {
+		log.Printf("删除上次未完成的临时集合 %s", tmpName)
+	}
+	tmp, err := kb.createCollection(ctx, tmpName, dimension)
+	if err != nil {
+		return err
+	}
+	if err := kb.addBatches(ctx, tmp, docs); err != nil {
+		if _, dropErr := kb.client.DeleteCollection(ctx, tmpName); dropErr != nil {
+			log.Printf("删除临时集合 %s 时出错: %v", tmpName, dropErr)
+		}
+		return fmt.Errorf("重新向量化失败，原集合未改动: %w", err)
+	}
+
+	if kb.collection != nil {
+		if _, err := kb.client.DeleteCollection(ctx, kb.collectionName); err != nil {
+			log.Printf("删除集合时出错: %v", err)
+			return fmt.Errorf("删除集合时出错，新向量保存在临时集合 %s 中: %w", tmpName, err)
+		}
+	}
+	metadata := tmp.Metadata
+	if _, err := tmp.Update(ctx, kb.collectionName, &metadata); err != nil {
+		log.Printf("重命名集合时出错: %v", err)
+		return fmt.Errorf("重命名临时集合 %s 失败，请手动改名为 %s: %w", tmpName, kb.collectionName, err)
+	}
+	kb.collection = tmp
+	kb.mismatchErr = nil
+	log.Printf("集合 %s 使用 %s 重新向量化 %d 条文档", kb.collectionName, kb.embeddingModel, len(docs))
+	return nil
+}
+
+// chromaStore Chroma 服务上的集合管理
+type chromaStore struct {
+	conf   *config.AppConfig
+	client *chroma.Client
+}
+
+func newChromaStore(conf *config.AppConfig) (*chromaStore, error) {
+	kb, err := NewChromaKB(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &chromaStore{conf: conf, client: kb.client}, nil
+}
+
+func (s *chromaStore) open(name string) (KnowledgeBaseI, error) {
+	conf := *s.conf
+	conf.CollectionName = name
+	return NewChromaKB(&conf)
+}
+
+func (s *chromaStore) list() ([]string, error) {
+	collections, err := s.client.ListCollections(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("列出集合时出错: %w", err)
+	}
+	names := make([]string, 0, len(collections))
+	for _, c := range collections {
+		if !strings.HasSuffix(c.Name, reembedSuffix) {
+			names = append(names, c.Name)
+		}
+	}
+	return names, nil
+}
+
+func (s *chromaStore) rename(oldName, newName string) error {
+	ctx := context.Background()
+	collections, err := s.client.ListCollections(ctx)
+	if err != nil {
+		return fmt.Errorf("列出集合时出错: %w", err)
+	}
+	for _, c := range collections {
+		if c.Name != oldName {
+			continue
+		}
+		// 保留集合元数据中记录的向量模型信息
+		metadata := c.Metadata
+		if _, err := c.Update(ctx, newName, &metadata); err != nil {
+			return fmt.Errorf("重命名集合失败: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("集合不存在: %s", oldName)
+}
+
+func (s *chromaStore) drop(name string) error {
+	if _, err := s.client.DeleteCollection(context.Background(), name); err != nil {
+		return fmt.Errorf("删除集合失败: %w", err)
+	}
+	return nil
+}
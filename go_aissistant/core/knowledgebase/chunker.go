@@ -0,0 +1,316 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+// 分块策略
+const (
+	ChunkFixed    = "fixed"    // 固定长度，相邻块之间带重叠
+	ChunkSentence = "sentence" // 按段落、句子边界聚合到目标长度
+	ChunkMarkdown = "markdown" // 先按 Markdown 标题切分章节，章节内再按句子聚合
+)
+
+const (
+	defaultChunkSize    = 800
+	defaultChunkOverlap = 100
+)
+
+// 分块写入的元数据键
+const (
+	MetaParentID    = "parent_id"
+	MetaChunkIndex  = "chunk_index"
+	MetaChunkCount  = "chunk_count"
+	MetaStartOffset = "start_offset"
+	MetaEndOffset   = "end_offset"
+	MetaHeading     = "heading"
+)
+
+// Chunker 把长文档切分为适合向量化的小块。
+// 长度和偏移量均以字符（rune）计，中文不会被截断为半个字。
+type Chunker struct {
+	Strategy  string
+	ChunkSize int
+	Overlap   int
+}
+
+func NewChunker(strategy string, size, overlap int) *Chunker {
+	if strategy == "" {
+		strategy = ChunkSentence
+	}
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	return &Chunker{Strategy: strategy, ChunkSize: size, Overlap: overlap}
+}
+
+// NewChunkerFromConfig 根据配置创建分块器，未配置时使用句子策略、800 字、重叠 100 字，
+// chunk_overlap 为负数表示不重叠
+func NewChunkerFromConfig(conf *config.AppConfig) *Chunker {
+	overlap := conf.ChunkOverlap
+	if overlap == 0 {
+		overlap = defaultChunkOverlap
+	}
+	return NewChunker(conf.ChunkStrategy, conf.ChunkSize, overlap)
+}
+
+// span 原文中的一段 [start, end)，以 rune 下标表示
+type span struct {
+	start, end int
+	heading    string
+}
+
+// Split 切分文档。每个块继承原文档的元数据，并记录父文档 ID、块序号和在原文中的字符偏移。
+func (c *Chunker) Split(doc Document) []Document {
+	text := []rune(doc.Text)
+
+	var spans []span
+	switch c.Strategy {
+	case ChunkFixed:
+		spans = c.splitFixed(text, span{start: 0, end: len(text)})
+	case ChunkMarkdown:
+		for _, section := range splitMarkdownSections(text) {
+			for _, s := range c.splitSentences(text, section) {
+				s.heading = section.heading
+				spans = append(spans, s)
+			}
+		}
+	default:
+		spans = c.splitSentences(text, span{start: 0, end: len(text)})
+	}
+
+	var chunks []Document
+	for _, s := range spans {
+		s = trimSpan(text, s)
+		if s.start >= s.end {
+			continue
+		}
+
+		metadata := make(map[string]interface{}, len(doc.Metadata)+6)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetaParentID] = doc.ID
+		metadata[MetaChunkIndex] = len(chunks)
+		metadata[MetaStartOffset] = s.start
+		metadata[MetaEndOffset] = s.end
+		if s.heading != "" {
+			metadata[MetaHeading] = s.heading
+		}
+
+		chunks = append(chunks, Document{
+			ID:       fmt.Sprintf("%s#%d", doc.ID, len(chunks)),
+			Text:     string(text[s.start:s.end]),
+			Metadata: metadata,
+		})
+	}
+
+	for i := range chunks {
+		chunks[i].Metadata[MetaChunkCount] = len(chunks)
+	}
+	return chunks
+}
+
+// splitFixed 按固定长度切分，切点尽量避开英文单词中间
+func (c *Chunker) splitFixed(text []rune, within span) []span {
+	var spans []span
+	step := c.ChunkSize - c.Overlap
+	for start := within.start; start < within.end; start += step {
+		end := start + c.ChunkSize
+		if end >= within.end {
+			spans = append(spans, span{start: start, end: within.end})
+			break
+		}
+		end = adjustWordBoundary(text, start, end)
+		spans = append(spans, span{start: start, end: end})
+		step = end - start - c.Overlap
+		if step <= 0 {
+			step = end - start
+		}
+	}
+	return spans
+}
+
+// adjustWordBoundary 若切点落在英文单词内部，向前回退到最近的空白处（最多回退块长的 1/5）
+func adjustWordBoundary(text []rune, start, end int) int {
+	if !isWordRune(text[end-1]) || !isWordRune(text[end]) {
+		return end
+	}
+	limit := end - (end-start)/5
+	for i := end - 1; i > limit; i-- {
+		if unicode.IsSpace(text[i]) {
+			return i + 1
+		}
+	}
+	return end
+}
+
+func isWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// splitSentences 把句子依次装入不超过 ChunkSize 的块，块之间重叠上一块末尾的若干句
+func (c *Chunker) splitSentences(text []rune, within span) []span {
+	sentences := sentenceSpans(text, within)
+
+	var spans []span
+	var current []span
+	length := 0
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		spans = append(spans, span{start: current[0].start, end: current[len(current)-1].end})
+
+		// 保留末尾不超过 Overlap 的句子作为下一块的开头
+		var keep []span
+		kept := 0
+		for i := len(current) - 1; i >= 0 && c.Overlap > 0; i-- {
+			l := current[i].end - current[i].start
+			if kept+l > c.Overlap {
+				break
+			}
+			keep = append([]span{current[i]}, keep...)
+			kept += l
+		}
+		current, length = keep, kept
+	}
+
+	for _, s := range sentences {
+		l := s.end - s.start
+		if l > c.ChunkSize {
+			// 超长句子单独按固定长度切分
+			flush()
+			current, length = nil, 0
+			spans = append(spans, c.splitFixed(text, s)...)
+			continue
+		}
+		if length+l > c.ChunkSize && len(current) > 0 {
+			flush()
+			if length+l > c.ChunkSize {
+				current, length = nil, 0
+			}
+		}
+		current = append(current, s)
+		length += l
+	}
+	if len(current) > 0 && (len(spans) == 0 || current[len(current)-1].end > spans[len(spans)-1].end) {
+		spans = append(spans, span{start: current[0].start, end: current[len(current)-1].end})
+	}
+	return spans
+}
+
+// sentenceSpans 在中英文句末标点和段落处断句，标点和后随的引号、空白归入前一句
+func sentenceSpans(text []rune, within span) []span {
+	var spans []span
+	start := within.start
+	for i := within.start; i < within.end; i++ {
+		r := text[i]
+		boundary := false
+		switch r {
+		case '。', '！', '？', '；', '!', '?', ';', '…':
+			boundary = true
+		case '.':
+			// 英文句号后需跟空白，避免切断小数和缩写
+			boundary = i+1 >= within.end || unicode.IsSpace(text[i+1])
+		case '\n':
+			// 空行分段
+			boundary = i+1 < within.end && text[i+1] == '\n'
+		}
+		if !boundary {
+			continue
+		}
+
+		end := i + 1
+		for end < within.end && strings.ContainsRune("”’\"')）」』\n \t", text[end]) {
+			end++
+		}
+		spans = append(spans, span{start: start, end: end})
+		start = end
+		i = end - 1
+	}
+	if start < within.end {
+		spans = append(spans, span{start: start, end: within.end})
+	}
+	return spans
+}
+
+// splitMarkdownSections 按 ATX 标题（# 开头的行）切分章节，代码块中的 # 不视为标题。
+// 每个章节记录从一级标题开始的标题路径，如 "安装 > 使用 Docker"。
+func splitMarkdownSections(text []rune) []span {
+	var sections []span
+	var headings []string
+	start := 0
+	heading := ""
+	inFence := false
+
+	lineStart := 0
+	for lineStart < len(text) {
+		lineEnd := lineStart
+		for lineEnd < len(text) && text[lineEnd] != '\n' {
+			lineEnd++
+		}
+		line := strings.TrimSpace(string(text[lineStart:lineEnd]))
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+		} else if level, title := parseMarkdownHeading(line); !inFence && level > 0 {
+			if lineStart > start {
+				sections = append(sections, span{start: start, end: lineStart, heading: heading})
+			}
+			if level <= len(headings) {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, title)
+			heading = joinHeadings(headings)
+			start = lineStart
+		}
+		lineStart = lineEnd + 1
+	}
+	if start < len(text) {
+		sections = append(sections, span{start: start, end: len(text), heading: heading})
+	}
+	return sections
+}
+
+// parseMarkdownHeading 解析 "## 标题" 形式的行，返回级别和标题文本
+func parseMarkdownHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+func joinHeadings(headings []string) string {
+	var parts []string
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func trimSpan(text []rune, s span) span {
+	for s.start < s.end && unicode.IsSpace(text[s.start]) {
+		s.start++
+	}
+	for s.end > s.start && unicode.IsSpace(text[s.end-1]) {
+		s.end--
+	}
+	return s
+}
@@ -0,0 +1,152 @@
+package knowledgebase
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type chunkSpan struct {
+	Text       string
+	Start, End int
+}
+
+func chunkSpans(chunks []Document) []chunkSpan {
+	spans := make([]chunkSpan, len(chunks))
+	for i, c := range chunks {
+		spans[i] = chunkSpan{
+			Text:  c.Text,
+			Start: c.Metadata[MetaStartOffset].(int),
+			End:   c.Metadata[MetaEndOffset].(int),
+		}
+	}
+	return spans
+}
+
+func TestChunkerSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunker *Chunker
+		text    string
+		want    []chunkSpan
+	}{
+		{
+			name:    "固定长度 ASCII 带重叠",
+			chunker: NewChunker(ChunkFixed, 10, 3),
+			text:    "abcdefghijklmnopqrstuvwxyz",
+			want: []chunkSpan{
+				{"abcdefghij", 0, 10},
+				{"hijklmnopq", 7, 17},
+				{"opqrstuvwx", 14, 24},
+				{"vwxyz", 21, 26},
+			},
+		},
+		{
+			name:    "固定长度中文按字计",
+			chunker: NewChunker(ChunkFixed, 6, 2),
+			text:    "一二三四五六七八九十甲乙丙丁",
+			want: []chunkSpan{
+				{"一二三四五六", 0, 6},
+				{"五六七八九十", 4, 10},
+				{"九十甲乙丙丁", 8, 14},
+			},
+		},
+		{
+			name:    "固定长度避开英文单词中间",
+			chunker: NewChunker(ChunkFixed, 20, 0),
+			text:    "aaaaaaaaa bbbbbbb cccccc dddd",
+			want: []chunkSpan{
+				{"aaaaaaaaa bbbbbbb", 0, 17},
+				{"cccccc dddd", 18, 29},
+			},
+		},
+		{
+			name:    "中文句子重叠上一块末尾的句子",
+			chunker: NewChunker(ChunkSentence, 8, 4),
+			text:    "第一句。第二句！第三句？",
+			want: []chunkSpan{
+				{"第一句。第二句！", 0, 8},
+				{"第二句！第三句？", 4, 12},
+			},
+		},
+		{
+			name:    "英文句子去掉首尾空白后记录偏移",
+			chunker: NewChunker(ChunkSentence, 15, -1),
+			text:    "Hello world. Foo bar. Baz.",
+			want: []chunkSpan{
+				{"Hello world.", 0, 12},
+				{"Foo bar. Baz.", 13, 26},
+			},
+		},
+		{
+			name:    "小数点不断句",
+			chunker: NewChunker(ChunkSentence, 10, 0),
+			text:    "版本 1.5 发布。修复若干问题。",
+			want: []chunkSpan{
+				{"版本 1.5 发布。", 0, 10},
+				{"修复若干问题。", 10, 17},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := tt.chunker.Split(Document{ID: "doc", Text: tt.text})
+			if got := chunkSpans(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkerOffsetsMatchText(t *testing.T) {
+	text := "# 安装\n\n下载安装包。运行 install.sh 即可，耗时约 2.5 分钟！\n\n## 使用 Docker\n\n" +
+		strings.Repeat("容器启动后访问 http://localhost:8080 。", 5) + "\n\n```\n# 不是标题\n```\n最后一段 text without CJK punctuation"
+	runes := []rune(text)
+	for _, strategy := range []string{ChunkFixed, ChunkSentence, ChunkMarkdown} {
+		chunks := NewChunker(strategy, 40, 10).Split(Document{ID: "doc", Text: text, Metadata: map[string]interface{}{"source": "a.md"}})
+		if len(chunks) < 2 {
+			t.Fatalf("%s: 只切出 %d 块", strategy, len(chunks))
+		}
+		for i, c := range chunks {
+			start := c.Metadata[MetaStartOffset].(int)
+			end := c.Metadata[MetaEndOffset].(int)
+			if got := string(runes[start:end]); got != c.Text {
+				t.Errorf("%s 块 %d: 偏移 [%d,%d) 对应 %q，块内容为 %q", strategy, i, start, end, got, c.Text)
+			}
+			if c.Metadata[MetaChunkIndex] != i || c.Metadata[MetaChunkCount] != len(chunks) || c.Metadata[MetaParentID] != "doc" {
+				t.Errorf("%s 块 %d: 元数据不正确 %v", strategy, i, c.Metadata)
+			}
+			if c.Metadata["source"] != "a.md" {
+				t.Errorf("%s 块 %d: 没有继承原文档的元数据", strategy, i)
+			}
+			if len([]rune(c.Text)) > 40 {
+				t.Errorf("%s 块 %d: 长度 %d 超过块长", strategy, i, len([]rune(c.Text)))
+			}
+		}
+	}
+}
+
+func TestChunkerMarkdownHeadings(t *testing.T) {
+	text := "# 安装\n说明。\n## 使用 Docker\n运行容器。\n```\n# 注释\n```\n# 配置\n修改文件。"
+	chunks := NewChunker(ChunkMarkdown, 100, 0).Split(Document{ID: "doc", Text: text})
+
+	var headings []string
+	for _, c := range chunks {
+		h, _ := c.Metadata[MetaHeading].(string)
+		headings = append(headings, h)
+	}
+	want := []string{"安装", "安装 > 使用 Docker", "配置"}
+	if !reflect.DeepEqual(headings, want) {
+		t.Errorf("标题路径 = %q, want %q", headings, want)
+	}
+}
+
+func TestNewChunkerOverlap(t *testing.T) {
+	if c := NewChunker("", 0, 0); c.Strategy != ChunkSentence || c.ChunkSize != defaultChunkSize {
+		t.Errorf("默认值不正确: %+v", c)
+	}
+	if c := NewChunker(ChunkFixed, 10, 10); c.Overlap != 0 {
+		t.Errorf("重叠不小于块长时应不重叠，得到 %d", c.Overlap)
+	}
+}
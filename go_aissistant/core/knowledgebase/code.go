@@ -0,0 +1,354 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 源码片段的元数据键
+const (
+	MetaPath       = "path"
+	MetaLanguage   = "language"
+	MetaSymbol     = "symbol"
+	MetaSymbolKind = "symbol_kind"
+	MetaStartLine  = "start_line"
+	MetaEndLine    = "end_line"
+)
+
+// codeLanguages 支持的源码扩展名 -> 语言
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".rs":    "rust",
+	".swift": "swift",
+	".php":   "php",
+}
+
+// parseCodeSections 按函数、类型等顶层定义切分源码，每个片段记录文件路径、语言、符号名和行号
+func parseCodeSections(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	language := codeLanguages[strings.ToLower(filepath.Ext(path))]
+	var sections []Section
+	switch language {
+	case "go":
+		sections, err = splitGoSource(path, content)
+		if err != nil {
+			// 语法错误的文件按通用规则切分
+			sections = splitBraceSource(string(content))
+		}
+	case "python":
+		sections = splitPythonSource(string(content))
+	default:
+		sections = splitBraceSource(string(content))
+	}
+
+	for i := range sections {
+		if sections[i].Metadata == nil {
+			sections[i].Metadata = map[string]interface{}{}
+		}
+		sections[i].Metadata[MetaPath] = path
+		sections[i].Metadata[MetaLanguage] = language
+	}
+	return sections, nil
+}
+
+// codeSection 由行号范围（从 1 开始，含两端）构造片段
+func codeSection(lines []string, start, end int, symbol, kind string) Section {
+	s := Section{
+		Title: symbol,
+		Text:  strings.Join(lines[start-1:end], "\n"),
+		Metadata: map[string]interface{}{
+			MetaStartLine: start,
+			MetaEndLine:   end,
+		},
+	}
+	if symbol != "" {
+		s.Metadata[MetaSymbol] = symbol
+		s.Metadata[MetaSymbolKind] = kind
+	}
+	return s
+}
+
+// splitGoSource 用 go/ast 解析，每个函数、方法、类型声明及其文档注释为一个片段，
+// 包声明、导入和包级变量、常量合并为一个文件头片段
+func splitGoSource(path string, content []byte) ([]Section, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("解析Go源码失败: %v", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+
+	var sections []Section
+	var header []string
+	header = append(header, "package "+file.Name.Name)
+	if file.Doc != nil {
+		header = append([]string{file.Doc.Text()}, header...)
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			start := line(d.Pos())
+			if d.Doc != nil {
+				start = line(d.Doc.Pos())
+			}
+			symbol, kind := d.Name.Name, "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol, kind = goReceiverType(d.Recv.List[0].Type)+"."+d.Name.Name, "method"
+			}
+			sections = append(sections, codeSection(lines, start, line(d.End()), symbol, kind))
+
+		case *ast.GenDecl:
+			start := line(d.Pos())
+			if d.Doc != nil {
+				start = line(d.Doc.Pos())
+			}
+			if d.Tok != token.TYPE {
+				if d.Tok != token.IMPORT {
+					header = append(header, strings.Join(lines[start-1:line(d.End())], "\n"))
+				}
+				continue
+			}
+			var names []string
+			for _, spec := range d.Specs {
+				names = append(names, spec.(*ast.TypeSpec).Name.Name)
+			}
+			sections = append(sections, codeSection(lines, start, line(d.End()), strings.Join(names, ", "), "type"))
+		}
+	}
+
+	if len(header) > 1 {
+		sections = append([]Section{{Title: "package " + file.Name.Name, Text: strings.Join(header, "\n\n")}}, sections...)
+	}
+	return sections, nil
+}
+
+func goReceiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverType(t.X)
+	case *ast.IndexExpr:
+		return goReceiverType(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+var pythonDef = regexp.MustCompile(`^(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`)
+
+// splitPythonSource 以顶格的 def/class（连同前面的装饰器）作为片段起点，
+// 其余顶层代码（导入、全局变量等）合并为模块片段
+func splitPythonSource(content string) []Section {
+	lines := strings.Split(content, "\n")
+
+	var sections []Section
+	var module []string
+	start, symbol, kind := 0, "", ""
+	closeBlock := func(end int) {
+		// 去掉末尾空行
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		if symbol != "" && end >= start {
+			sections = append(sections, codeSection(lines, start, end, symbol, kind))
+		}
+		symbol = ""
+	}
+
+	for i, l := range lines {
+		if l == "" || strings.ContainsRune(" \t)]}#", rune(l[0])) {
+			// 缩进行、空行、注释和多行表达式的结尾属于当前块
+			if symbol == "" {
+				module = append(module, l)
+			}
+			continue
+		}
+		if strings.HasPrefix(l, "@") {
+			// 装饰器属于紧随其后的定义
+			if symbol == "" || kind != "decorator" {
+				closeBlock(i)
+				start, symbol, kind = i+1, "@", "decorator"
+			}
+			continue
+		}
+		if m := pythonDef.FindStringSubmatch(l); m != nil {
+			if kind != "decorator" {
+				closeBlock(i)
+				start = i + 1
+			}
+			symbol, kind = m[2], "function"
+			if m[1] == "class" {
+				kind = "class"
+			}
+			continue
+		}
+		closeBlock(i)
+		module = append(module, l)
+	}
+	closeBlock(len(lines))
+
+	if text := strings.TrimSpace(strings.Join(module, "\n")); text != "" {
+		sections = append([]Section{{Title: "module", Text: text}}, sections...)
+	}
+	return sections
+}
+
+var (
+	braceDefKeyword = regexp.MustCompile(`\b(?:class|struct|interface|enum|trait|impl|namespace|object|fn|func|function|union)\s+([A-Za-z_$][\w$]*)`)
+	braceDefCall    = regexp.MustCompile(`([A-Za-z_$][\w$:~]*)\s*\([^()]*\)?\s*(?:const\s*)?(?:->[^{]*|:[^{]*|throws[^{]*)?\{?\s*$`)
+)
+
+// splitBraceSource 适用于 C 风格语言的启发式切分：顶层的 { ... } 块（连同前面的签名和注释）
+// 为一个片段，符号名取类型关键字后的名称或左括号前的标识符
+func splitBraceSource(content string) []Section {
+	lines := strings.Split(content, "\n")
+
+	var sections []Section
+	var toplevel []string
+	depth := 0
+	blockStart := -1 // 当前顶层块的起始行（从 1 开始）
+	pending := 0     // 可能属于下一个块的签名、注释起始行
+	inComment := false
+
+	for i, l := range lines {
+		lineNo := i + 1
+		code := stripCodeLiterals(l, &inComment)
+		trimmed := strings.TrimSpace(l)
+
+		if depth == 0 && blockStart < 0 {
+			if trimmed == "" {
+				pending = 0
+			} else if pending == 0 {
+				pending = lineNo
+			}
+		}
+
+		opens, closes := strings.Count(code, "{"), strings.Count(code, "}")
+		if depth == 0 && opens > closes && blockStart < 0 {
+			blockStart = pending
+			if blockStart == 0 {
+				blockStart = lineNo
+			}
+		}
+		depth += opens - closes
+		if depth < 0 {
+			depth = 0
+		}
+
+		switch {
+		case blockStart > 0 && depth == 0:
+			// 顶层块结束
+			signature := strings.Join(lines[blockStart-1:lineNo], "\n")
+			symbol, kind := braceSymbol(signature)
+			sections = append(sections, codeSection(lines, blockStart, lineNo, symbol, kind))
+			blockStart, pending = -1, 0
+		case blockStart < 0 && depth == 0 && (strings.HasSuffix(trimmed, ";") || strings.HasPrefix(trimmed, "#")):
+			// 顶层语句（导入、声明、预处理指令）
+			toplevel = append(toplevel, strings.Join(lines[max(pending, 1)-1:lineNo], "\n"))
+			pending = 0
+		}
+	}
+	if blockStart > 0 {
+		symbol, kind := braceSymbol(strings.Join(lines[blockStart-1:], "\n"))
+		sections = append(sections, codeSection(lines, blockStart, len(lines), symbol, kind))
+	}
+
+	if text := strings.TrimSpace(strings.Join(toplevel, "\n")); text != "" {
+		sections = append([]Section{{Title: "toplevel", Text: text}}, sections...)
+	}
+	return sections
+}
+
+// braceSymbol 从块的开头（签名之前可能有注释）提取符号名和类型
+func braceSymbol(block string) (string, string) {
+	var signature []string
+	for _, l := range strings.Split(block, "\n") {
+		t := strings.TrimSpace(l)
+		if t == "" || strings.HasPrefix(t, "//") || strings.HasPrefix(t, "/*") || strings.HasPrefix(t, "*") || strings.HasPrefix(t, "@") {
+			continue
+		}
+		signature = append(signature, t)
+		if strings.Contains(t, "{") {
+			break
+		}
+	}
+	sig := strings.Join(signature, " ")
+	if i := strings.Index(sig, "{"); i >= 0 {
+		sig = sig[:i+1]
+	}
+
+	if m := braceDefKeyword.FindStringSubmatch(sig); m != nil {
+		kind := strings.Fields(m[0])[0]
+		switch kind {
+		case "fn", "func", "function":
+			kind = "function"
+		}
+		return m[1], kind
+	}
+	if m := braceDefCall.FindStringSubmatch(sig); m != nil {
+		switch m[1] {
+		case "if", "for", "while", "switch", "catch":
+		default:
+			return m[1], "function"
+		}
+	}
+	return "", ""
+}
+
+// stripCodeLiterals 去掉一行中的字符串、字符字面量和注释，避免其中的括号影响块的判断
+func stripCodeLiterals(line string, inComment *bool) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if *inComment {
+			if strings.HasPrefix(line[i:], "*/") {
+				*inComment = false
+				i++
+			}
+			continue
+		}
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "//"):
+			return b.String()
+		case strings.HasPrefix(line[i:], "/*"):
+			*inComment = true
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			for i++; i < len(line) && line[i] != c; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
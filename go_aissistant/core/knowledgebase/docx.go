@@ -0,0 +1,366 @@
+package knowledgebase
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DOCX、ODT 等文档各部分在 Section.Title 中的名称
+const (
+	sectionBody      = "正文"
+	sectionHeader    = "页眉"
+	sectionFooter    = "页脚"
+	sectionFootnotes = "脚注"
+	sectionEndnotes  = "尾注"
+	sectionComments  = "批注"
+)
+
+var (
+	docxHeaderPart = regexp.MustCompile(`^word/header\d*\.xml$`)
+	docxFooterPart = regexp.MustCompile(`^word/footer\d*\.xml$`)
+	docxHeadingID  = regexp.MustCompile(`(?i)^heading\s*(\d)$`)
+)
+
+// parseDocxSections 解析 OOXML 文档：正文中的标题转为 Markdown 标题，列表项转为 "- " 开头的行，
+// 表格按行输出、单元格以 " | " 分隔；页眉页脚、脚注、尾注和批注作为附加片段（includeExtras 为 false 时忽略）
+func parseDocxSections(path string, includeExtras bool) ([]Section, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开DOCX文件失败: %v", err)
+	}
+	defer r.Close()
+
+	parts := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+
+	doc, ok := parts["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("无效的DOCX文件: 缺少 word/document.xml")
+	}
+
+	styles := map[string]int{}
+	if f, ok := parts["word/styles.xml"]; ok {
+		if styles, err = readDocxPart(f, parseDocxStyles); err != nil {
+			return nil, err
+		}
+	}
+
+	extract := func(f *zip.File) (string, error) {
+		return readDocxPart(f, func(r io.Reader) (string, error) {
+			return extractDocxText(r, styles)
+		})
+	}
+
+	body, err := extract(doc)
+	if err != nil {
+		return nil, err
+	}
+	sections := []Section{{Title: sectionBody, Text: body}}
+	if !includeExtras {
+		return sections, nil
+	}
+
+	// 页眉页脚常按首页、奇偶页重复定义，合并时去重
+	var names []string
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	extras := []struct {
+		title string
+		match func(string) bool
+	}{
+		{sectionHeader, docxHeaderPart.MatchString},
+		{sectionFooter, docxFooterPart.MatchString},
+		{sectionFootnotes, func(name string) bool { return name == "word/footnotes.xml" }},
+		{sectionEndnotes, func(name string) bool { return name == "word/endnotes.xml" }},
+		{sectionComments, func(name string) bool { return name == "word/comments.xml" }},
+	}
+	for _, extra := range extras {
+		var texts []string
+		seen := map[string]bool{}
+		for _, name := range names {
+			if !extra.match(name) {
+				continue
+			}
+			text, err := extract(parts[name])
+			if err != nil {
+				return nil, err
+			}
+			if text = strings.TrimSpace(text); text != "" && !seen[text] {
+				seen[text] = true
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) > 0 {
+			sections = append(sections, Section{Title: extra.title, Text: strings.Join(texts, "\n\n")})
+		}
+	}
+	return sections, nil
+}
+
+func readDocxPart[T any](f *zip.File, parse func(io.Reader) (T, error)) (T, error) {
+	var zero T
+	rc, err := f.Open()
+	if err != nil {
+		return zero, fmt.Errorf("读取 %s 失败: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	result, err := parse(rc)
+	if err != nil {
+		return zero, fmt.Errorf("解析 %s 失败: %v", f.Name, err)
+	}
+	return result, nil
+}
+
+// parseDocxStyles 从 styles.xml 中找出标题样式，返回样式 ID -> 标题级别
+func parseDocxStyles(r io.Reader) (map[string]int, error) {
+	levels := map[string]int{}
+	decoder := xml.NewDecoder(r)
+
+	var styleID string
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return levels, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "style":
+			styleID = xmlAttr(start, "styleId")
+			if m := docxHeadingID.FindStringSubmatch(styleID); m != nil {
+				levels[styleID], _ = strconv.Atoi(m[1])
+			}
+		case "name":
+			name := strings.ToLower(xmlAttr(start, "val"))
+			if m := docxHeadingID.FindStringSubmatch(name); m != nil {
+				levels[styleID], _ = strconv.Atoi(m[1])
+			} else if name == "title" {
+				levels[styleID] = 1
+			}
+		case "outlineLvl":
+			// 大纲级别从 0 开始，9 表示正文
+			if lvl, err := strconv.Atoi(xmlAttr(start, "val")); err == nil && lvl < 9 && styleID != "" {
+				if _, ok := levels[styleID]; !ok {
+					levels[styleID] = lvl + 1
+				}
+			}
+		}
+	}
+}
+
+// docxParagraph 正在解析的段落
+type docxParagraph struct {
+	text      strings.Builder
+	heading   int
+	listLevel int // 列表缩进级别，-1 表示不是列表项
+}
+
+// extractDocxText 遍历 WordprocessingML 的段落、表格和脚注，输出带有轻量 Markdown 标记的纯文本
+func extractDocxText(r io.Reader, styles map[string]int) (string, error) {
+	decoder := xml.NewDecoder(r)
+
+	var out strings.Builder
+	// 文本框中的段落嵌套在外层段落内，用栈保存未结束的段落，para 为栈顶
+	var paras []*docxParagraph
+	var para *docxParagraph
+	var lastWasList bool
+
+	// 表格：只由最外层表格产生行，嵌套表格的内容并入所在单元格
+	tableDepth := 0
+	var row []string
+	var cell strings.Builder
+	inCell := false
+
+	notePrefix := ""
+	skipDepth := 0 // 脚注分隔符等需要跳过的元素
+	inText := false
+	inParaProps := false
+
+	writeBlock := func(text string, list bool) {
+		if out.Len() > 0 {
+			if list && lastWasList {
+				out.WriteString("\n")
+			} else {
+				out.WriteString("\n\n")
+			}
+		}
+		out.WriteString(text)
+		lastWasList = list
+	}
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+
+			switch t.Name.Local {
+			case "footnote", "endnote":
+				// 分隔线等特殊脚注没有正文
+				if typ := xmlAttr(t, "type"); typ != "" && typ != "normal" {
+					skipDepth = 1
+					continue
+				}
+				// 编号写在脚注第一段之前
+				if id := xmlAttr(t, "id"); id != "" {
+					notePrefix = "[" + id + "] "
+				}
+			case "tbl":
+				// 表格与前面的列表之间空一行
+				if tableDepth == 0 {
+					lastWasList = false
+				}
+				tableDepth++
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+					inCell = true
+				}
+			case "p":
+				para = &docxParagraph{listLevel: -1}
+				paras = append(paras, para)
+			case "pPr":
+				inParaProps = true
+			case "pStyle":
+				if para != nil && inParaProps {
+					para.heading = styles[xmlAttr(t, "val")]
+				}
+			case "outlineLvl":
+				if para != nil && inParaProps {
+					if lvl, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && lvl < 9 {
+						para.heading = lvl + 1
+					}
+				}
+			case "ilvl":
+				if para != nil && inParaProps {
+					para.listLevel, _ = strconv.Atoi(xmlAttr(t, "val"))
+				}
+			case "numPr":
+				if para != nil && inParaProps && para.listLevel < 0 {
+					para.listLevel = 0
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if para != nil && !inParaProps {
+					para.text.WriteString("\t")
+				}
+			case "br", "cr":
+				if para != nil {
+					para.text.WriteString("\n")
+				}
+			case "footnoteReference", "endnoteReference":
+				if para != nil {
+					para.text.WriteString("[" + xmlAttr(t, "id") + "]")
+				}
+			case "delText", "instrText":
+				// 修订中删除的文字和域代码不属于正文
+				skipDepth = 1
+			}
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "pPr":
+				inParaProps = false
+			case "p":
+				if para == nil {
+					continue
+				}
+				text := strings.TrimSpace(para.text.String())
+				heading, listLevel := para.heading, para.listLevel
+				paras = paras[:len(paras)-1]
+				para = nil
+				if len(paras) > 0 {
+					para = paras[len(paras)-1]
+				}
+				if text == "" {
+					continue
+				}
+				if !inCell && notePrefix != "" {
+					text, notePrefix = notePrefix+text, ""
+				}
+
+				switch {
+				case inCell:
+					if cell.Len() > 0 {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(strings.ReplaceAll(text, "\n", " "))
+				case heading > 0:
+					writeBlock(strings.Repeat("#", min(heading, 6))+" "+strings.ReplaceAll(text, "\n", " "), false)
+				case listLevel >= 0:
+					writeBlock(strings.Repeat("  ", listLevel)+"- "+text, true)
+				default:
+					writeBlock(text, false)
+				}
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.TrimSpace(cell.String()))
+					inCell = false
+				}
+			case "tr":
+				if tableDepth == 1 && len(row) > 0 {
+					writeBlock(strings.Join(row, " | "), true)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					lastWasList = false
+				}
+			}
+
+		case xml.CharData:
+			if inText && skipDepth == 0 && para != nil {
+				para.text.Write(t)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+func xmlAttr(start xml.StartElement, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
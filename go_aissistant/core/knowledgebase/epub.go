@@ -0,0 +1,211 @@
+package knowledgebase
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MetaChapter 电子书章节序号，从 1 开始
+const MetaChapter = "chapter"
+
+// parseEPUBSections 按书脊（spine）顺序解析电子书，每个章节文件为一个片段。
+// 章节标题优先取目录中的名称，其次取正文中的第一个标题；书名记录在 title 元数据中
+func parseEPUBSections(filePath string) ([]Section, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开EPUB文件失败: %v", err)
+	}
+	defer r.Close()
+
+	parts := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+
+	container, ok := parts["META-INF/container.xml"]
+	if !ok {
+		return nil, fmt.Errorf("无效的EPUB文件: 缺少 META-INF/container.xml")
+	}
+	root, err := readDocxPart(container, parseXMLTree)
+	if err != nil {
+		return nil, err
+	}
+	rootfile := root.find("rootfile")
+	if rootfile == nil || parts[rootfile.attr("full-path")] == nil {
+		return nil, fmt.Errorf("无效的EPUB文件: 找不到 OPF 文件")
+	}
+	opfPath := rootfile.attr("full-path")
+	opf, err := readDocxPart(parts[opfPath], parseXMLTree)
+	if err != nil {
+		return nil, err
+	}
+	opfDir := path.Dir(opfPath)
+
+	bookTitle := ""
+	if metadata := opf.find("metadata"); metadata != nil {
+		if t := metadata.find("title"); t != nil {
+			bookTitle = strings.TrimSpace(t.text())
+		}
+	}
+
+	type manifestItem struct {
+		path, mediaType, properties string
+	}
+	items := map[string]manifestItem{}
+	if manifest := opf.find("manifest"); manifest != nil {
+		for _, item := range manifest.findAll("item") {
+			items[item.attr("id")] = manifestItem{
+				path:       epubPath(opfDir, item.attr("href")),
+				mediaType:  item.attr("media-type"),
+				properties: item.attr("properties"),
+			}
+		}
+	}
+
+	// 目录：EPUB 3 的导航文档或 EPUB 2 的 NCX
+	toc := map[string]string{}
+	spine := opf.find("spine")
+	if spine == nil {
+		return nil, fmt.Errorf("无效的EPUB文件: OPF 中缺少 spine")
+	}
+	for _, item := range items {
+		if strings.Contains(item.properties, "nav") && parts[item.path] != nil {
+			if err := epubNavTitles(parts[item.path], toc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(toc) == 0 {
+		if ncx, ok := items[spine.attr("toc")]; ok && parts[ncx.path] != nil {
+			if err := epubNCXTitles(parts[ncx.path], toc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var sections []Section
+	for _, ref := range spine.findAll("itemref") {
+		item, ok := items[ref.attr("idref")]
+		if !ok || parts[item.path] == nil || strings.Contains(item.properties, "nav") {
+			continue
+		}
+		if item.mediaType != "" && !strings.Contains(item.mediaType, "html") {
+			continue
+		}
+
+		doc, err := readDocxPart(parts[item.path], func(r io.Reader) (*html.Node, error) {
+			return html.Parse(r)
+		})
+		if err != nil {
+			return nil, err
+		}
+		pageTitle, text := htmlDocumentText(doc)
+		if strings.TrimSpace(text) == "" {
+			// 封面等只有图片的页面
+			continue
+		}
+
+		title := toc[item.path]
+		if title == "" {
+			for _, a := range []atom.Atom{atom.H1, atom.H2, atom.H3} {
+				if n := findHTMLElement(doc, a); n != nil {
+					title = htmlInlineText(n, false)
+					break
+				}
+			}
+		}
+		if title == "" && pageTitle != bookTitle {
+			title = pageTitle
+		}
+		if title == "" {
+			title = fmt.Sprintf("第 %d 章", len(sections)+1)
+		}
+
+		metadata := map[string]interface{}{MetaChapter: len(sections) + 1}
+		if bookTitle != "" {
+			metadata["title"] = bookTitle
+		}
+		sections = append(sections, Section{Title: title, Text: text, Metadata: metadata})
+	}
+	return sections, nil
+}
+
+// epubPath 把相对于 OPF 或目录文件的链接转为压缩包中的路径，去掉锚点
+func epubPath(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(dir, href)
+}
+
+// epubNavTitles 从 EPUB 3 导航文档的 <nav epub:type="toc"> 中读取章节文件 -> 标题，同一文件取第一个标题
+func epubNavTitles(f *zip.File, toc map[string]string) error {
+	doc, err := readDocxPart(f, func(r io.Reader) (*html.Node, error) {
+		return html.Parse(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	nav := findHTMLElement(doc, atom.Nav)
+	if nav == nil {
+		return nil
+	}
+	dir := path.Dir(f.Name)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" {
+					p := epubPath(dir, attr.Val)
+					if _, ok := toc[p]; !ok {
+						toc[p] = htmlInlineText(n, false)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(nav)
+	return nil
+}
+
+// epubNCXTitles 从 EPUB 2 的 toc.ncx 中读取章节文件 -> 标题
+func epubNCXTitles(f *zip.File, toc map[string]string) error {
+	root, err := readDocxPart(f, parseXMLTree)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Dir(f.Name)
+	var walk func(*xmlNode)
+	walk = func(n *xmlNode) {
+		for _, point := range n.Children {
+			if point.Name.Local != "navPoint" {
+				continue
+			}
+			label, content := point.find("navLabel"), point.find("content")
+			if label != nil && content != nil {
+				p := epubPath(dir, content.attr("src"))
+				if _, ok := toc[p]; !ok {
+					toc[p] = strings.TrimSpace(label.text())
+				}
+			}
+			walk(point)
+		}
+	}
+	if navMap := root.find("navMap"); navMap != nil {
+		walk(navMap)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+)
+
+// 改写问题时参考的最近对话消息数，以及每条消息最多保留的字符数
+const (
+	rewriteHistoryMessages = 6
+	rewriteMessageChars    = 500
+)
+
+// QueryExpander 检索前用大模型处理问题：结合对话历史改写为独立的问题，
+// 可选生成若干种不同问法以及一段假设的答案（HyDE），分别检索后合并
+type QueryExpander struct {
+	Client ai_model.Provider
+	// Model 为空时由调用方在使用前设置为当前对话的模型
+	Model string
+
+	Rewrite     bool // 有对话历史时改写为独立的问题
+	Paraphrases int  // 额外生成的问法数
+	HyDE        bool // 生成假设的答案一起检索
+}
+
+// NewQueryExpanderFromConfig 按配置创建，改写、扩展和 HyDE 都未开启时返回 nil
+func NewQueryExpanderFromConfig(conf *config.AppConfig, client ai_model.Provider) *QueryExpander {
+	if !conf.QueryRewrite && conf.QueryExpansions <= 0 && !conf.HyDE {
+		return nil
+	}
+	return &QueryExpander{
+		Client:      client,
+		Model:       conf.QueryRewriteModel,
+		Rewrite:     conf.QueryRewrite,
+		Paraphrases: max(conf.QueryExpansions, 0),
+		HyDE:        conf.HyDE,
+	}
+}
+
+// Expand 返回用于检索的查询，第一条是（改写后的）问题本身。
+// 大模型调用失败时只记录日志，至少返回原问题
+func (e *QueryExpander) Expand(ctx context.Context, question string, history []ai_model.Message) []string {
+	standalone := question
+	if e.Rewrite && len(history) > 0 {
+		rewritten, err := e.rewrite(ctx, question, history)
+		if err != nil {
+			log.Printf("改写问题失败: %v", err)
+		} else if rewritten != "" {
+			standalone = rewritten
+		}
+	}
+
+	var paraphrases []string
+	var hypothetical string
+	var wg sync.WaitGroup
+	if e.Paraphrases > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if paraphrases, err = e.paraphrase(ctx, standalone); err != nil {
+				log.Printf("生成同义问法失败: %v", err)
+			}
+		}()
+	}
+	if e.HyDE {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if hypothetical, err = e.hypothesize(ctx, standalone); err != nil {
+				log.Printf("生成假设答案失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	queries := []string{standalone}
+	for _, q := range append(paraphrases, hypothetical) {
+		if q != "" && !containsFold(queries, q) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+func (e *QueryExpander) rewrite(ctx context.Context, question string, history []ai_model.Message) (string, error) {
+	if len(history) > rewriteHistoryMessages {
+		history = history[len(history)-rewriteHistoryMessages:]
+	}
+	var b strings.Builder
+	for _, m := range history {
+		role := "用户"
+		if m.Role == ai_model.RoleAssistant {
+			role = "助手"
+		}
+		content := []rune(m.Content)
+		if len(content) > rewriteMessageChars {
+			content = append(content[:rewriteMessageChars], []rune("…")...)
+		}
+		b.WriteString(fmt.Sprintf("%s：%s\n", role, string(content)))
+	}
+
+	prompt := fmt.Sprintf("下面是一段对话历史和用户的最新问题。请把最新问题改写为一个不依赖对话历史、可以单独用于检索资料的完整问题，"+
+		"补全其中的指代和省略。问题本身已经完整时原样输出。只输出改写后的问题，不要解释。\n\n对话历史：\n%s\n最新问题：%s", b.String(), question)
+	reply, err := e.chat(ctx, prompt, &ai_model.Options{Temperature: ai_model.Float32(0), NumPredict: ai_model.Int(128)})
+	if err != nil {
+		return "", err
+	}
+	return firstLine(reply), nil
+}
+
+func (e *QueryExpander) paraphrase(ctx context.Context, question string) ([]string, error) {
+	prompt := fmt.Sprintf("请为下面的问题写出 %d 种不同的问法，换用不同的说法和关键词，用于检索资料。"+
+		"每行一个，不要编号，不要解释。\n\n问题：%s", e.Paraphrases, question)
+	reply, err := e.chat(ctx, prompt, &ai_model.Options{Temperature: ai_model.Float32(0.7), NumPredict: ai_model.Int(64 * e.Paraphrases)})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, line := range strings.Split(reply, "\n") {
+		if line = cleanLine(line); line != "" && len(result) < e.Paraphrases {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+func (e *QueryExpander) hypothesize(ctx context.Context, question string) (string, error) {
+	prompt := "请用一段话（约 100 字）直接回答下面的问题，写成资料中可能出现的陈述句。" +
+		"不确定时也给出最可能的答案，不要说明自己不确定。\n\n问题：" + question
+	reply, err := e.chat(ctx, prompt, &ai_model.Options{Temperature: ai_model.Float32(0.3), NumPredict: ai_model.Int(256)})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply), nil
+}
+
+func (e *QueryExpander) chat(ctx context.Context, prompt string, opts *ai_model.Options) (string, error) {
+	return e.Client.Chat(ctx, e.Model, []ai_model.Message{{Role: ai_model.RoleUser, Content: prompt}}, opts)
+}
+
+// 行首的编号或列表符号，如 "1. "、"2）"、"- "
+var listMarkerPattern = regexp.MustCompile(`^(\d+[.、)）]|[-*•])\s*`)
+
+// cleanLine 去掉行首的编号和首尾的引号、空白
+func cleanLine(line string) string {
+	return trimQuotes(listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+}
+
+func trimQuotes(s string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "\"'“”「」"))
+}
+
+// firstLine 取回复中第一个非空行
+func firstLine(reply string) string {
+	for _, line := range strings.Split(reply, "\n") {
+		if line = trimQuotes(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// QueryMulti 对每个查询分别在 opts.Collections 中检索，再按倒数排名融合，
+// 只有一个查询时与 QueryCollections 相同
+func (km *KnowledgeBaseManager) QueryMulti(queries []string, opts QueryOptions) ([]Document, error) {
+	if len(queries) == 1 {
+		return km.QueryCollections(queries[0], opts)
+	}
+
+	var lists [][]Document
+	var weights []float64
+	var lastErr error
+	for _, q := range queries {
+		docs, err := km.QueryCollections(q, opts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		lists = append(lists, docs)
+		weights = append(weights, 1)
+	}
+	if len(lists) == 0 {
+		return nil, lastErr
+	}
+	return fuseRanks(lists, weights, opts.NumResults), nil
+}
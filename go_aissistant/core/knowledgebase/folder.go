@@ -0,0 +1,232 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FolderOptions 文件夹导入的过滤规则。
+// 模式不含 "/" 时与文件名或目录名匹配，如 "*.md"、"node_modules"；
+// 含 "/" 时与相对于导入目录的路径匹配，"**" 匹配任意层目录，如 "docs/**/*.pdf"
+type FolderOptions struct {
+	Include []string // 只导入匹配的文件，为空时导入全部支持的格式
+	Exclude []string // 跳过匹配的文件和目录
+}
+
+// ImportResult 单个文件的导入结果
+type ImportResult struct {
+	Path   string
+	Chunks int
+	Err    error
+}
+
+// ParsePatterns 把逗号、分号或换行分隔的列表拆分为切片，去掉各项首尾的空白，名称中间可以有空格
+func ParsePatterns(text string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '，' || r == ';' || r == '\n'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ScanFolder 递归列出目录下可以导入的文件，隐藏目录（以 . 开头）总是跳过
+func (im *Importer) ScanFolder(root string, opts FolderOptions) ([]string, error) {
+	filter, err := im.newFolderFilter(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	return filter.walk(root, nil)
+}
+
+// folderFilter 按 FolderOptions 判断目录下的文件是否需要导入，扫描和监听共用
+type folderFilter struct {
+	root      string
+	include   []globPattern
+	exclude   []globPattern
+	supported func(string) bool
+}
+
+func (im *Importer) newFolderFilter(root string, opts FolderOptions) (*folderFilter, error) {
+	include, err := compileGlobs(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &folderFilter{root: root, include: include, exclude: exclude, supported: im.Supported}, nil
+}
+
+// walk 递归列出 dir 下需要导入的文件，onDir 不为空时对进入的每个目录调用一次
+func (f *folderFilter) walk(dir string, onDir func(string) error) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if !f.dir(path) {
+				return filepath.SkipDir
+			}
+			if onDir != nil {
+				return onDir(path)
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && f.file(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描目录失败: %v", err)
+	}
+	return files, nil
+}
+
+// rel 返回相对于导入目录的路径，不在目录下时返回 false
+func (f *folderFilter) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(f.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// dir 是否进入目录，导入目录本身总是进入
+func (f *folderFilter) dir(path string) bool {
+	rel, ok := f.rel(path)
+	if !ok {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+	return !strings.HasPrefix(filepath.Base(path), ".") && !matchAny(f.exclude, rel)
+}
+
+// file 文件是否导入，不检查上级目录是否被排除
+func (f *folderFilter) file(path string) bool {
+	rel, ok := f.rel(path)
+	if !ok || rel == "." || !f.supported(path) || matchAny(f.exclude, rel) {
+		return false
+	}
+	return len(f.include) == 0 || matchAny(f.include, rel)
+}
+
+// contains 文件是否导入，且所在的各级目录都没有被排除
+func (f *folderFilter) contains(path string) bool {
+	if !f.file(path) {
+		return false
+	}
+	for dir := filepath.Dir(path); dir != f.root; dir = filepath.Dir(dir) {
+		if !f.dir(dir) || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportFiles 用最多 Workers 个协程并发导入文件，每个文件完成后调用 progress（可能来自不同协程）。
+// ctx 取消后不再开始新的文件，正在处理的文件完成后返回 ctx.Err()
+func (im *Importer) ImportFiles(ctx context.Context, kb KnowledgeBaseI, files []string, progress func(ImportResult)) error {
+	return forEach(ctx, im.Workers, files, func(path string) {
+		n, err := im.ImportFile(kb, path)
+		if progress != nil {
+			progress(ImportResult{Path: path, Chunks: n, Err: err})
+		}
+	})
+}
+
+// forEach 用最多 workers 个协程处理各项，ctx 取消后不再开始新的任务，等待进行中的任务完成后返回 ctx.Err()
+func forEach(ctx context.Context, workers int, items []string, fn func(string)) error {
+	workers = min(max(workers, 1), max(len(items), 1))
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				fn(item)
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for _, item := range items {
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
+
+type globPattern struct {
+	re       *regexp.Regexp
+	pathOnly bool // 模式含 "/"，只与相对路径匹配
+}
+
+func compileGlobs(patterns []string) ([]globPattern, error) {
+	var globs []globPattern
+	for _, p := range patterns {
+		p = strings.Trim(filepath.ToSlash(strings.TrimSpace(p)), "/")
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile("^" + globRegexp(p) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("无效的匹配模式 %s: %v", p, err)
+		}
+		globs = append(globs, globPattern{re: re, pathOnly: strings.Contains(p, "/")})
+	}
+	return globs, nil
+}
+
+// globRegexp 把 glob 模式转为正则：* 和 ? 不跨越目录，** 匹配任意层目录
+func globRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+func matchAny(globs []globPattern, rel string) bool {
+	name := rel[strings.LastIndex(rel, "/")+1:]
+	for _, g := range globs {
+		if g.re.MatchString(rel) || !g.pathOnly && g.re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
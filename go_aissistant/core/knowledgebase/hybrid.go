@@ -0,0 +1,152 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+// 倒数排名融合的平滑常数，得分为 权重/(rrfK+名次)
+const rrfK = 60
+
+// 混合检索时每种检索方式取的候选数为结果数的倍数
+const hybridCandidates = 3
+
+// hybridKB 在向量库之上维护同一批文档的关键词索引，按 retrieval_mode 使用向量检索、关键词检索或两者融合。
+// 关键词索引在第一次检索时从向量库读取全部文档建立，此后随写入和删除增量更新
+type hybridKB struct {
+	KnowledgeBaseI
+	conf *config.AppConfig
+
+	indexMu sync.Mutex // 保证建立索引期间的写入不会遗漏
+	index   *KeywordIndex
+	loaded  bool
+}
+
+func newHybridKB(kb KnowledgeBaseI, conf *config.AppConfig) *hybridKB {
+	return &hybridKB{KnowledgeBaseI: kb, conf: conf, index: NewKeywordIndex()}
+}
+
+// keywordIndex 返回关键词索引，尚未建立时先从向量库加载
+func (h *hybridKB) keywordIndex() (*KeywordIndex, error) {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+
+	if !h.loaded {
+		docs, err := h.KnowledgeBaseI.ListDocuments()
+		if err != nil {
+			return nil, fmt.Errorf("建立关键词索引失败: %v", err)
+		}
+		h.index.Add(docs)
+		h.loaded = true
+	}
+	return h.index, nil
+}
+
+// updateIndex 索引已建立时同步修改；尚未建立时不需要处理，建立时会读到最新的文档
+func (h *hybridKB) updateIndex(update func(*KeywordIndex)) {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+	if h.loaded {
+		update(h.index)
+	}
+}
+
+func (h *hybridKB) AddDocuments(docs []Document) error {
+	if err := h.KnowledgeBaseI.AddDocuments(docs); err != nil {
+		return err
+	}
+	h.updateIndex(func(index *KeywordIndex) { index.Add(docs) })
+	return nil
+}
+
+func (h *hybridKB) DeleteDocument(id string) error {
+	if err := h.KnowledgeBaseI.DeleteDocument(id); err != nil {
+		return err
+	}
+	h.updateIndex(func(index *KeywordIndex) { index.Remove(id) })
+	return nil
+}
+
+func (h *hybridKB) DeleteBySource(path string, keep ...string) error {
+	if err := h.KnowledgeBaseI.DeleteBySource(path, keep...); err != nil {
+		return err
+	}
+	h.updateIndex(func(index *KeywordIndex) { index.RemoveSource(path, keep...) })
+	return nil
+}
+
+// Query 混合检索时向量和关键词各取若干候选后融合；其中一种检索失败（如向量模型不可用）时只使用另一种的结果
+func (h *hybridKB) Query(query string, opts QueryOptions) ([]Document, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = h.conf.RetrievalMode
+	}
+
+	switch mode {
+	case config.RetrievalVector:
+		return h.KnowledgeBaseI.Query(query, opts)
+	case config.RetrievalKeyword:
+		index, err := h.keywordIndex()
+		if err != nil {
+			return nil, err
+		}
+		return index.Search(query, opts), nil
+	case config.RetrievalHybrid, "":
+	default:
+		return nil, fmt.Errorf("不支持的检索方式: %s", mode)
+	}
+
+	candidates := opts
+	candidates.NumResults = opts.NumResults * hybridCandidates
+
+	vectorDocs, vectorErr := h.KnowledgeBaseI.Query(query, candidates)
+	var keywordDocs []Document
+	index, keywordErr := h.keywordIndex()
+	if keywordErr == nil {
+		keywordDocs = index.Search(query, candidates)
+	}
+
+	switch {
+	case vectorErr != nil && keywordErr != nil:
+		return nil, vectorErr
+	case vectorErr != nil:
+		log.Printf("向量检索失败，只使用关键词检索结果: %v", vectorErr)
+	case keywordErr != nil:
+		log.Printf("关键词检索失败，只使用向量检索结果: %v", keywordErr)
+	}
+	return fuseRanks([][]Document{vectorDocs, keywordDocs}, []float64{h.conf.VectorWeight, h.conf.KeywordWeight}, opts.NumResults), nil
+}
+
+// fuseRanks 按倒数排名融合（RRF）合并多组已排序的结果，同一 ID 的文档得分相加，保留最先出现的一份。
+// 结果的 Score 除以各组都排第一时的得分，取值 0-1
+func fuseRanks(lists [][]Document, weights []float64, numResults int) []Document {
+	scores := map[string]float64{}
+	docs := map[string]Document{}
+	var order []string
+	var best float64
+	for i, list := range lists {
+		best += weights[i] / (rrfK + 1)
+		for rank, doc := range list {
+			if _, ok := docs[doc.ID]; !ok {
+				docs[doc.ID] = doc
+				order = append(order, doc.ID)
+			}
+			scores[doc.ID] += weights[i] / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > numResults {
+		order = order[:numResults]
+	}
+	result := make([]Document, len(order))
+	for i, id := range order {
+		result[i] = docs[id]
+		result[i].Score = float32(scores[id] / best)
+	}
+	return result
+}
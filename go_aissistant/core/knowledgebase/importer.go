@@ -0,0 +1,155 @@
+package knowledgebase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+const defaultImportWorkers = 2
+
+// 导入时记录的文件状态，监听文件夹时据此判断文件是否变化
+const (
+	MetaHash    = "hash"  // 文件内容的 SHA-256
+	MetaModTime = "mtime" // 文件修改时间，Unix 毫秒
+)
+
+// Importer 解析文件、切分为块后写入知识库，界面和命令行导入共用
+type Importer struct {
+	Parser  *FileParser
+	Chunker *Chunker
+	// Workers 导入多个文件时并发处理的文件数
+	Workers int
+	// Versions 不为空时记录每个文件的导入版本
+	Versions *VersionStore
+	// Tags 写入每块元数据的标签，检索时可以按标签过滤
+	Tags []string
+}
+
+func NewImporterFromConfig(conf *config.AppConfig) *Importer {
+	workers := conf.ImportWorkers
+	if workers <= 0 {
+		workers = defaultImportWorkers
+	}
+	return &Importer{
+		Parser:  NewFileParserFromConfig(conf),
+		Chunker: NewChunkerFromConfig(conf),
+		Workers: workers,
+	}
+}
+
+// Supported 文件格式是否可以导入
+func (im *Importer) Supported(path string) bool {
+	return im.Parser.SupportedFormats[strings.ToLower(filepath.Ext(path))]
+}
+
+// FileDocuments 解析文件并切分为待写入的块，元数据中记录文件名、完整路径、导入日期、文件类型、标签以及文件的哈希和修改时间
+func (im *Importer) FileDocuments(path string) ([]Document, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	hash, modTime, err := fileState(path)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := im.Parser.ParseSections(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"source":     filepath.Base(path),
+		MetaPath:     path,
+		"date":       time.Now().Format("2006-01-02"),
+		MetaHash:     hash,
+		MetaModTime:  modTime,
+		MetaFileType: strings.ToLower(filepath.Ext(path)),
+	}
+	if len(im.Tags) > 0 {
+		// Chroma 的元数据不支持数组，标签以逗号分隔保存
+		metadata[MetaTags] = strings.Join(im.Tags, ",")
+	}
+	docs := SectionDocuments(sourceDocID(path, hash), sections, metadata)
+
+	// 切分为多个块后再写入，避免整篇文档只有一个向量
+	var chunks []Document
+	for _, doc := range docs {
+		chunks = append(chunks, im.Chunker.Split(doc)...)
+	}
+	return chunks, nil
+}
+
+// ImportFile 导入单个文件，返回写入的块数。再次导入同一文件时替换该文件原有的块
+func (im *Importer) ImportFile(kb KnowledgeBaseI, path string) (int, error) {
+	chunks, err := im.FileDocuments(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("文件中没有可导入的文本: %s", filepath.Base(path))
+	}
+	if err := im.replaceSource(kb, chunks); err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// replaceSource 先写入新块，成功后再删除来源文件中不在新块里的旧块，写入失败时知识库中仍是旧块；内容有变化时记录新版本。
+// 块 ID 由路径和内容哈希生成，内容未变时写入的 ID 与原来相同
+func (im *Importer) replaceSource(kb KnowledgeBaseI, chunks []Document) error {
+	path, _ := chunks[0].Metadata[MetaPath].(string)
+	hash, _ := chunks[0].Metadata[MetaHash].(string)
+	if err := kb.AddDocuments(chunks); err != nil {
+		return err
+	}
+	if err := kb.DeleteBySource(path, documentIDs(chunks)...); err != nil {
+		return err
+	}
+	if im.Versions != nil {
+		if _, err := im.Versions.Record(kb.Name(), path, hash, chunks, ""); err != nil {
+			log.Printf("记录版本失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func documentIDs(docs []Document) []string {
+	ids := make([]string, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+// fileState 返回文件内容的 SHA-256 和修改时间
+func fileState(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", 0, fmt.Errorf("读取文件失败: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), info.ModTime().UnixMilli(), nil
+}
+
+// sourceDocID 由文件路径和内容哈希生成稳定的文档 ID，同一文件的相同内容总是得到相同的 ID
+func sourceDocID(path, hash string) string {
+	sum := sha256.Sum256([]byte(path))
+	return fmt.Sprintf("doc-%s-%s", hex.EncodeToString(sum[:6]), hash[:12])
+}
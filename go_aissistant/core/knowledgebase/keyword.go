@@ -0,0 +1,220 @@
+package knowledgebase
+
+import (
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordIndex 内存中的倒排索引，按 BM25 对文档打分，用于精确匹配编号、错误码、产品名等向量检索容易漏掉的词
+type KeywordIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]*keywordDoc
+	postings map[string]map[string]int // 词 -> 文档 ID -> 词频
+	totalLen int
+}
+
+type keywordDoc struct {
+	doc    Document
+	length int
+	terms  map[string]int
+}
+
+func NewKeywordIndex() *KeywordIndex {
+	return &KeywordIndex{
+		docs:     map[string]*keywordDoc{},
+		postings: map[string]map[string]int{},
+	}
+}
+
+// Add 索引文档，ID 已存在时替换
+func (ki *KeywordIndex) Add(docs []Document) {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+
+	for _, doc := range docs {
+		ki.remove(doc.ID)
+
+		terms := map[string]int{}
+		tokens := tokenize(doc.Text, true)
+		for _, t := range tokens {
+			terms[t]++
+		}
+		doc.Score = 0
+		doc.Metadata = maps.Clone(doc.Metadata)
+		ki.docs[doc.ID] = &keywordDoc{doc: doc, length: len(tokens), terms: terms}
+		ki.totalLen += len(tokens)
+		for t, n := range terms {
+			if ki.postings[t] == nil {
+				ki.postings[t] = map[string]int{}
+			}
+			ki.postings[t][doc.ID] = n
+		}
+	}
+}
+
+// Remove 移除文档
+func (ki *KeywordIndex) Remove(id string) {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+	ki.remove(id)
+}
+
+// RemoveSource 移除元数据 path 等于该路径的全部文档，ID 在 keep 中的除外
+func (ki *KeywordIndex) RemoveSource(path string, keep ...string) {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+	for id, d := range ki.docs {
+		if p, _ := d.doc.Metadata[MetaPath].(string); p == path && !kept[id] {
+			ki.remove(id)
+		}
+	}
+}
+
+func (ki *KeywordIndex) remove(id string) {
+	d, ok := ki.docs[id]
+	if !ok {
+		return
+	}
+	for t := range d.terms {
+		delete(ki.postings[t], id)
+		if len(ki.postings[t]) == 0 {
+			delete(ki.postings, t)
+		}
+	}
+	ki.totalLen -= d.length
+	delete(ki.docs, id)
+}
+
+// Search 按 BM25 得分从高到低返回满足过滤条件的文档，最多 opts.NumResults 条。
+// 结果的 Score 为相对于最高分的比例，取值 0-1；opts.MinScore 针对向量相似度，这里不使用
+func (ki *KeywordIndex) Search(query string, opts QueryOptions) []Document {
+	ki.mu.RLock()
+	defer ki.mu.RUnlock()
+
+	if len(ki.docs) == 0 || opts.NumResults <= 0 {
+		return nil
+	}
+
+	n := float64(len(ki.docs))
+	avgLen := float64(ki.totalLen) / n
+	scores := map[string]float64{}
+	seen := map[string]bool{}
+	for _, t := range Tokenize(query) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		posting := ki.postings[t]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range posting {
+			f := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(ki.docs[id].length)/avgLen
+			scores[id] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	opts.MinScore = 0
+	var docs []Document
+	for _, id := range ids {
+		if len(docs) >= opts.NumResults {
+			break
+		}
+		doc := ki.docs[id].doc
+		if !opts.Match(doc) {
+			continue
+		}
+		// 元数据是索引自己的，返回副本，调用方修改时不影响索引
+		doc.Metadata = maps.Clone(doc.Metadata)
+		doc.Score = float32(scores[id] / scores[ids[0]])
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// Tokenize 把文本切分为检索用的词：字母数字连续的部分（含下划线）作为一个词并转为小写，
+// 中日韩文字没有空格分词，按相邻两字切分（单独的一个字作为一个词）
+func Tokenize(text string) []string {
+	return tokenize(text, false)
+}
+
+// tokenize unigrams 为 true 时连续的中日韩文字除相邻两字外还输出每个单字，
+// 用于建立索引，使只有一个字的查询也能匹配到包含这个字的词
+func tokenize(text string, unigrams bool) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+			if unigrams {
+				for _, r := range cjk {
+					tokens = append(tokens, string(r))
+				}
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isIdeographic(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// isIdeographic 中日韩文字，不含 isCJK 包括的全角标点
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,204 @@
+package knowledgebase
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text     string
+		unigrams bool
+		want     []string
+	}{
+		{"Error_Code E1001, v2.5", false, []string{"error_code", "e1001", "v2", "5"}},
+		{"中文检索", false, []string{"中文", "文检", "检索"}},
+		{"中文检索", true, []string{"中文", "文检", "检索", "中", "文", "检", "索"}},
+		{"用 Docker 部署", false, []string{"用", "docker", "部署"}},
+		{"用 Docker 部署", true, []string{"用", "docker", "部署", "部", "署"}},
+		{"、。！", true, nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text, tt.unigrams); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q, %v) = %q, want %q", tt.text, tt.unigrams, got, tt.want)
+		}
+	}
+}
+
+func searchIDs(ki *KeywordIndex, query string, opts QueryOptions) []string {
+	var ids []string
+	for _, d := range ki.Search(query, opts) {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func TestKeywordIndexSearch(t *testing.T) {
+	ki := NewKeywordIndex()
+	ki.Add([]Document{
+		{ID: "a", Text: "错误码 E1001 表示连接超时", Metadata: map[string]interface{}{"source": "a.txt"}},
+		{ID: "b", Text: "E1001 E1001 重试后仍然出现 E1001", Metadata: map[string]interface{}{"source": "b.txt"}},
+		{ID: "c", Text: "配置文件说明，与错误码无关的一段很长很长很长的文字", Metadata: map[string]interface{}{"source": "c.txt"}},
+		{ID: "d", Text: "安装步骤", Metadata: map[string]interface{}{"source": "d.txt"}},
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		opts  QueryOptions
+		want  []string
+	}{
+		{"词频高的排在前面", "e1001", QueryOptions{NumResults: 10}, []string{"b", "a"}},
+		{"多个词的得分相加", "E1001 错误码", QueryOptions{NumResults: 10}, []string{"a", "b", "c"}},
+		{"结果数限制", "E1001 错误码", QueryOptions{NumResults: 1}, []string{"a"}},
+		{"来源过滤", "E1001", QueryOptions{NumResults: 10, Sources: []string{"a.txt"}}, []string{"a"}},
+		{"单个汉字匹配词中的字", "装", QueryOptions{NumResults: 10}, []string{"d"}},
+		{"单个汉字和两字词一起检索", "超时 错", QueryOptions{NumResults: 10}, []string{"a", "c"}},
+		{"没有匹配", "docker", QueryOptions{NumResults: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIDs(ki, tt.query, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	docs := ki.Search("E1001 错误码", QueryOptions{NumResults: 10})
+	if docs[0].Score != 1 {
+		t.Errorf("最高分应归一化为 1，得到 %v", docs[0].Score)
+	}
+	for i := 1; i < len(docs); i++ {
+		if docs[i].Score <= 0 || docs[i].Score > docs[i-1].Score {
+			t.Errorf("得分未按从高到低排列: %v", docs)
+		}
+	}
+}
+
+func TestKeywordIndexSearchReturnsCopies(t *testing.T) {
+	ki := NewKeywordIndex()
+	added := []Document{{ID: "x", Text: "alpha", Metadata: map[string]interface{}{MetaPath: "/x"}}}
+	ki.Add(added)
+	added[0].Metadata["added"] = "leaked"
+
+	results := ki.Search("alpha", QueryOptions{NumResults: 1})
+	if len(results) != 1 {
+		t.Fatalf("得到 %d 条结果", len(results))
+	}
+	results[0].Metadata[MetaCollection] = "leaked"
+
+	want := map[string]interface{}{MetaPath: "/x"}
+	if got := ki.docs["x"].doc.Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("修改写入的文档或检索结果后索引中的元数据 = %v, want %v", got, want)
+	}
+	if got := ki.Search("alpha", QueryOptions{NumResults: 1})[0].Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("再次检索得到的元数据 = %v, want %v", got, want)
+	}
+}
+
+// checkIndexConsistent 检查倒排表和文档总长度与当前文档一致
+func checkIndexConsistent(t *testing.T, ki *KeywordIndex) {
+	t.Helper()
+	totalLen := 0
+	postings := map[string]map[string]int{}
+	for id, d := range ki.docs {
+		totalLen += d.length
+		for term, n := range d.terms {
+			if postings[term] == nil {
+				postings[term] = map[string]int{}
+			}
+			postings[term][id] = n
+		}
+	}
+	if ki.totalLen != totalLen {
+		t.Errorf("totalLen = %d, 实际为 %d", ki.totalLen, totalLen)
+	}
+	if !reflect.DeepEqual(ki.postings, postings) {
+		t.Errorf("倒排表与文档不一致: %v, want %v", ki.postings, postings)
+	}
+}
+
+func TestKeywordIndexRemove(t *testing.T) {
+	ki := NewKeywordIndex()
+	ki.Add([]Document{
+		{ID: "a1", Text: "alpha beta", Metadata: map[string]interface{}{MetaPath: "/a.txt"}},
+		{ID: "a2", Text: "beta gamma 中文", Metadata: map[string]interface{}{MetaPath: "/a.txt"}},
+		{ID: "b", Text: "gamma delta", Metadata: map[string]interface{}{MetaPath: "/b.txt"}},
+	})
+	checkIndexConsistent(t, ki)
+
+	// 相同 ID 重新写入时替换，不重复计入长度
+	ki.Add([]Document{{ID: "b", Text: "delta", Metadata: map[string]interface{}{MetaPath: "/b.txt"}}})
+	checkIndexConsistent(t, ki)
+	if _, ok := ki.postings["gamma"]["b"]; ok {
+		t.Error("替换后旧内容的词仍在倒排表中")
+	}
+
+	ki.RemoveSource("/a.txt", "a2")
+	checkIndexConsistent(t, ki)
+	if _, ok := ki.docs["a2"]; !ok || len(ki.docs) != 2 {
+		t.Errorf("RemoveSource 应保留指定的文档，剩余 %d 条", len(ki.docs))
+	}
+	if _, ok := ki.postings["alpha"]; ok {
+		t.Error("没有文档包含的词应从倒排表中删除")
+	}
+
+	ki.Remove("missing")
+	ki.Remove("b")
+	ki.RemoveSource("/a.txt")
+	checkIndexConsistent(t, ki)
+	if len(ki.docs) != 0 || len(ki.postings) != 0 || ki.totalLen != 0 {
+		t.Errorf("全部删除后索引不为空: docs=%d postings=%d totalLen=%d", len(ki.docs), len(ki.postings), ki.totalLen)
+	}
+	if got := ki.Search("beta", QueryOptions{NumResults: 10}); got != nil {
+		t.Errorf("空索引检索应没有结果，得到 %v", got)
+	}
+}
+
+func TestFuseRanks(t *testing.T) {
+	docs := func(ids ...string) []Document {
+		list := make([]Document, len(ids))
+		for i, id := range ids {
+			list[i] = Document{ID: id}
+		}
+		return list
+	}
+	vector := docs("a", "b", "c")
+	keyword := docs("c", "d")
+
+	tests := []struct {
+		name    string
+		weights []float64
+		n       int
+		want    []string
+	}{
+		{"权重相同时两组都命中的排第一", []float64{1, 1}, 10, []string{"c", "a", "b", "d"}},
+		{"关键词权重高", []float64{0.2, 1}, 10, []string{"c", "d", "a", "b"}},
+		{"关键词权重很低时接近向量检索的顺序", []float64{1, 0.01}, 10, []string{"a", "b", "c", "d"}},
+		{"关键词权重为 0 时顺序同向量检索", []float64{1, 0}, 3, []string{"a", "b", "c"}},
+		{"结果数限制", []float64{1, 1}, 2, []string{"c", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := fuseRanks([][]Document{vector, keyword}, tt.weights, tt.n)
+			var ids []string
+			for _, d := range result {
+				ids = append(ids, d.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("fuseRanks = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	// 两组都排第一的文档得分为 1
+	result := fuseRanks([][]Document{docs("a", "b"), docs("a")}, []float64{1, 0.5}, 10)
+	if result[0].Score != 1 {
+		t.Errorf("两组都排第一的得分 = %v, want 1", result[0].Score)
+	}
+	want := (1.0 / (rrfK + 2)) / (1.0/(rrfK+1) + 0.5/(rrfK+1))
+	if math.Abs(float64(result[1].Score)-want) > 1e-6 {
+		t.Errorf("得分 = %v, want %v", result[1].Score, want)
+	}
+}
@@ -0,0 +1,752 @@
+package knowledgebase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	defaultLocalKBDir = "./kb_data"
+	localKBFileName   = "knowledge.db"
+	localEmbedTimeout = 2 * time.Minute
+	// 按 ID 批量读取文档时每条语句最多的 ID 数，低于 SQLite 的参数个数上限
+	localFetchBatch = 256
+)
+
+// Embedder 文本向量化接口，ai_model 中的各提供方均已实现
+type Embedder interface {
+	Embed(ctx context.Context, model string, input []string) ([][]float32, error)
+}
+
+// localVector 内存中的向量索引项，向量已归一化，点积即余弦相似度
+type localVector struct {
+	id     string
+	vector []float32
+}
+
+// LocalKB 纯 Go 的本地向量库：文档和向量持久化在 SQLite 文件中，
+// 查询时在内存中做暴力余弦检索，无需额外的 Chroma 服务
+type LocalKB struct {
+	db             *sql.DB
+	collectionName string
+	embedder       Embedder
+
+	embeddingModel     string
+	embeddingDimension int
+	batchSize          int
+
+	mu          sync.RWMutex
+	vectors     []localVector
+	mismatchErr error
+}
+
+// NewLocalKB 在 ChromaPath 目录（默认 ./kb_data）下打开或创建 knowledge.db
+func NewLocalKB(conf *config.AppConfig) (*LocalKB, error) {
+	db, err := openLocalKBFile(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newLocalKB(db, conf, conf.CollectionName), nil
+}
+
+func openLocalKBFile(conf *config.AppConfig) (*sql.DB, error) {
+	dir := conf.ChromaPath
+	if dir == "" {
+		dir = defaultLocalKBDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建知识库目录失败: %v", err)
+	}
+
+	return openLocalDB(filepath.Join(dir, localKBFileName))
+}
+
+// newLocalKB 在已打开的数据库上访问指定集合，多个集合共用同一个数据库文件
+func newLocalKB(db *sql.DB, conf *config.AppConfig, collectionName string) *LocalKB {
+	embeddingModel := conf.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = defaultEmbeddingModel
+	}
+	batchSize := conf.EmbeddingBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+
+	return &LocalKB{
+		db:                 db,
+		collectionName:     collectionName,
+		embedder:           ai_model.NewOllamaClient(conf.OllamaURL),
+		embeddingModel:     embeddingModel,
+		embeddingDimension: conf.EmbeddingDimension,
+		batchSize:          batchSize,
+	}
+}
+
+func openLocalDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Printf("打开本地知识库失败: %v", err)
+		return nil, fmt.Errorf("打开本地知识库失败: %v", err)
+	}
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
+		log.Printf("设置WAL模式失败: %v", err)
+		return nil, fmt.Errorf("设置WAL模式失败: %v", err)
+	}
+
+	createTableSQL := `
+    CREATE TABLE IF NOT EXISTS kb_collections (
+        name TEXT PRIMARY KEY,
+        embedding_model TEXT NOT NULL,
+        embedding_dimension INTEGER NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS kb_documents (
+        collection TEXT NOT NULL,
+        id TEXT NOT NULL,
+        text TEXT NOT NULL,
+        metadata TEXT,
+        embedding BLOB NOT NULL,
+        PRIMARY KEY (collection, id)
+    );
+    `
+	if _, err := db.Exec(createTableSQL); err != nil {
+		log.Printf("创建知识库表失败: %v", err)
+		return nil, fmt.Errorf("创建知识库表失败: %v", err)
+	}
+	return db, nil
+}
+
+func (kb *LocalKB) Name() string {
+	return kb.collectionName
+}
+
+// Initialize 加载集合中的全部向量到内存；集合不存在时按当前向量模型创建
+func (kb *LocalKB) Initialize() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	ctx := context.Background()
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	var model string
+	var existing int
+	err = kb.db.QueryRow(`SELECT embedding_model, embedding_dimension FROM kb_collections WHERE name = ?`,
+		kb.collectionName).Scan(&model, &existing)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := kb.db.Exec(`INSERT INTO kb_collections(name, embedding_model, embedding_dimension) VALUES(?, ?, ?)`,
+			kb.collectionName, kb.embeddingModel, dimension); err != nil {
+			return fmt.Errorf("创建集合失败: %v", err)
+		}
+	case err != nil:
+		return fmt.Errorf("读取集合信息失败: %v", err)
+	case model != kb.embeddingModel:
+		kb.mismatchErr = fmt.Errorf("%w: 集合 %s 使用 %s，当前配置为 %s", ErrEmbeddingMismatch, kb.collectionName, model, kb.embeddingModel)
+	case existing != dimension:
+		kb.mismatchErr = fmt.Errorf("%w: 集合 %s 的向量维度为 %d，当前模型 %s 为 %d", ErrEmbeddingMismatch, kb.collectionName, existing, kb.embeddingModel, dimension)
+	}
+	if kb.mismatchErr != nil {
+		log.Printf("%v", kb.mismatchErr)
+		return kb.mismatchErr
+	}
+
+	return kb.loadVectors()
+}
+
+func (kb *LocalKB) probeDimension(ctx context.Context) (int, error) {
+	vectors, err := kb.embed(ctx, []string{"dimension probe"})
+	if err != nil {
+		return 0, err
+	}
+	dimension := len(vectors[0])
+	if kb.embeddingDimension > 0 && kb.embeddingDimension != dimension {
+		return 0, fmt.Errorf("配置的向量维度 %d 与模型 %s 实际维度 %d 不一致", kb.embeddingDimension, kb.embeddingModel, dimension)
+	}
+	return dimension, nil
+}
+
+func (kb *LocalKB) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, localEmbedTimeout)
+	defer cancel()
+	vectors, err := kb.embedder.Embed(ctx, kb.embeddingModel, texts)
+	if err != nil {
+		log.Printf("调用向量模型 %s 失败: %v", kb.embeddingModel, err)
+		return nil, fmt.Errorf("调用向量模型 %s 失败: %w", kb.embeddingModel, err)
+	}
+	for _, v := range vectors {
+		normalize(v)
+	}
+	return vectors, nil
+}
+
+func (kb *LocalKB) loadVectors() error {
+	rows, err := kb.db.Query(`SELECT id, embedding FROM kb_documents WHERE collection = ?`, kb.collectionName)
+	if err != nil {
+		return fmt.Errorf("加载向量失败: %v", err)
+	}
+	defer rows.Close()
+
+	kb.vectors = kb.vectors[:0]
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return fmt.Errorf("扫描行失败: %v", err)
+		}
+		kb.vectors = append(kb.vectors, localVector{id: id, vector: decodeVector(blob)})
+	}
+	return rows.Err()
+}
+
+// AddDocuments 分批生成向量并写入，ID 相同的文档会被覆盖。
+// 生成向量时不持有写锁，文件夹导入的多个任务可以并发写入
+func (kb *LocalKB) AddDocuments(docs []Document) error {
+	kb.mu.RLock()
+	err := kb.mismatchErr
+	kb.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for start := 0; start < len(docs); start += kb.batchSize {
+		batch := docs[start:min(start+kb.batchSize, len(docs))]
+		vectors, err := kb.embedBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		kb.mu.Lock()
+		err = kb.upsert(batch, vectors)
+		kb.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addBatches 分批生成向量并写入，调用方持有写锁
+func (kb *LocalKB) addBatches(ctx context.Context, docs []Document) error {
+	for start := 0; start < len(docs); start += kb.batchSize {
+		batch := docs[start:min(start+kb.batchSize, len(docs))]
+		vectors, err := kb.embedBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		if err := kb.upsert(batch, vectors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kb *LocalKB) embedBatch(ctx context.Context, batch []Document) ([][]float32, error) {
+	texts := make([]string, len(batch))
+	for i, doc := range batch {
+		texts[i] = doc.Text
+	}
+	return kb.embed(ctx, texts)
+}
+
+func (kb *LocalKB) upsert(docs []Document, vectors [][]float32) error {
+	tx, err := kb.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+        INSERT OR REPLACE INTO kb_documents(collection, id, text, metadata, embedding)
+        VALUES(?, ?, ?, ?, ?)
+    `)
+	if err != nil {
+		return fmt.Errorf("准备语句失败: %v", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+		if ids[i] == "" {
+			ids[i] = fmt.Sprintf("doc-%d-%d", time.Now().UnixNano(), i)
+		}
+		metadata, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return fmt.Errorf("序列化元数据失败: %v", err)
+		}
+		if _, err := stmt.Exec(kb.collectionName, ids[i], doc.Text, string(metadata), encodeVector(vectors[i])); err != nil {
+			return fmt.Errorf("写入文档失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	// 同步内存索引
+	replaced := make(map[string][]float32, len(ids))
+	for i, id := range ids {
+		replaced[id] = vectors[i]
+	}
+	for i := range kb.vectors {
+		if v, ok := replaced[kb.vectors[i].id]; ok {
+			kb.vectors[i].vector = v
+			delete(replaced, kb.vectors[i].id)
+		}
+	}
+	for _, id := range ids {
+		if v, ok := replaced[id]; ok {
+			kb.vectors = append(kb.vectors, localVector{id: id, vector: v})
+			delete(replaced, id)
+		}
+	}
+	return nil
+}
+
+// Query 暴力计算查询向量与全部文档的余弦相似度，按相似度从高到低读取文档并筛选，
+// 直到取满 NumResults 条或相似度低于 MinScore
+func (kb *LocalKB) Query(query string, opts QueryOptions) ([]Document, error) {
+	if empty, err := kb.checkQueryable(); empty || err != nil || opts.NumResults <= 0 {
+		return nil, err
+	}
+
+	// 调用向量模型可能较慢，在加锁前完成，避免阻塞写入
+	vectors, err := kb.embed(context.Background(), []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	if kb.mismatchErr != nil {
+		return nil, kb.mismatchErr
+	}
+
+	// 来源和日期条件先在 SQL 中筛出候选，只计算这些文档的相似度
+	allowed, err := kb.filterIDs(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type scored struct {
+		id    string
+		score float32
+	}
+	scores := make([]scored, 0, len(kb.vectors))
+	for _, v := range kb.vectors {
+		if allowed == nil || allowed[v.id] {
+			scores = append(scores, scored{id: v.id, score: dot(q, v.vector)})
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+
+	// 按相似度分批读取文档，批次从结果数的两倍开始逐步加大
+	var docs []Document
+	batch := max(opts.NumResults*2, 16)
+	for start := 0; start < len(scores) && len(docs) < opts.NumResults; start += batch {
+		if start > 0 {
+			batch = min(batch*2, localFetchBatch)
+		}
+		end := min(start+batch, len(scores))
+		ids := make([]string, 0, end-start)
+		batchScores := make(map[string]float32, end-start)
+		for _, s := range scores[start:end] {
+			if s.score < opts.MinScore {
+				break
+			}
+			ids = append(ids, s.id)
+			batchScores[s.id] = s.score
+		}
+		found, err := kb.getDocuments(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range found {
+			doc.Score = batchScores[doc.ID]
+			if len(docs) < opts.NumResults && opts.Match(doc) {
+				docs = append(docs, doc)
+			}
+		}
+		if len(ids) < end-start {
+			break
+		}
+	}
+	return docs, nil
+}
+
+// filterIDs 用 SQL 按来源和日期条件筛选文档 ID，没有这两类条件时返回 nil 表示不限制。
+// 结果可能多于 Match 接受的文档，最终仍由 Match 判断
+func (kb *LocalKB) filterIDs(opts QueryOptions) (map[string]bool, error) {
+	var conds []string
+	args := []interface{}{kb.collectionName}
+	// SQLite 的 lower 只转换 ASCII 字母，含其他字符时交给 Match 按 Unicode 比较
+	if len(opts.Sources) > 0 && isASCII(strings.Join(opts.Sources, "")) {
+		placeholders := make([]string, len(opts.Sources))
+		for i, s := range opts.Sources {
+			placeholders[i] = "?"
+			args = append(args, strings.ToLower(strings.TrimSpace(s)))
+		}
+		conds = append(conds, fmt.Sprintf(`lower(json_extract(metadata, '$.source')) IN (%s)`, strings.Join(placeholders, ", ")))
+	}
+	if opts.DateFrom != "" {
+		conds = append(conds, `json_extract(metadata, '$.date') >= ?`)
+		args = append(args, opts.DateFrom)
+	}
+	if opts.DateTo != "" {
+		conds = append(conds, `json_extract(metadata, '$.date') <= ?`)
+		args = append(args, opts.DateTo)
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	rows, err := kb.db.Query(`SELECT id FROM kb_documents WHERE collection = ? AND `+strings.Join(conds, " AND "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("筛选文档失败: %v", err)
+	}
+	defer rows.Close()
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描行失败: %v", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// checkQueryable 集合为空时返回 true，向量模型不一致时返回错误
+func (kb *LocalKB) checkQueryable() (bool, error) {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	if kb.mismatchErr != nil {
+		return false, kb.mismatchErr
+	}
+	return len(kb.vectors) == 0, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// getDocuments 按给定 ID 顺序读取文档，每条语句最多读取 localFetchBatch 个，不存在的 ID 跳过
+func (kb *LocalKB) getDocuments(ids []string) ([]Document, error) {
+	found := make(map[string]Document, len(ids))
+	for start := 0; start < len(ids); start += localFetchBatch {
+		batch := ids[start:min(start+localFetchBatch, len(ids))]
+		args := make([]interface{}, 0, len(batch)+1)
+		args = append(args, kb.collectionName)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		rows, err := kb.db.Query(`SELECT id, text, metadata FROM kb_documents WHERE collection = ? AND id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("读取文档失败: %v", err)
+		}
+		for rows.Next() {
+			var id, text string
+			var metadata sql.NullString
+			if err := rows.Scan(&id, &text, &metadata); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("读取文档失败: %v", err)
+			}
+			found[id] = Document{ID: id, Text: text, Metadata: decodeMetadata(metadata)}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取文档失败: %v", err)
+		}
+	}
+
+	docs := make([]Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := found[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (kb *LocalKB) DeleteDocument(id string) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	result, err := kb.db.Exec(`DELETE FROM kb_documents WHERE collection = ? AND id = ?`, kb.collectionName, id)
+	if err != nil {
+		log.Printf("删除文档时出错: %v", err)
+		return fmt.Errorf("删除文档时出错: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		log.Printf("未找到要删除的文档: %s", id)
+		return fmt.Errorf("未找到要删除的文档: %s", id)
+	}
+
+	for i, v := range kb.vectors {
+		if v.id == id {
+			kb.vectors = append(kb.vectors[:i], kb.vectors[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteBySource 按元数据中的 path 删除文档并同步内存索引
+func (kb *LocalKB) DeleteBySource(path string, keep ...string) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	tx, err := kb.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM kb_documents WHERE collection = ? AND json_extract(metadata, '$.path') = ?`,
+		kb.collectionName, path)
+	if err != nil {
+		return fmt.Errorf("查询文档失败: %v", err)
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+	deleted := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("扫描行失败: %v", err)
+		}
+		if !kept[id] {
+			deleted[id] = true
+		}
+	}
+	rows.Close()
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	for id := range deleted {
+		if _, err := tx.Exec(`DELETE FROM kb_documents WHERE collection = ? AND id = ?`, kb.collectionName, id); err != nil {
+			log.Printf("删除文档时出错: %v", err)
+			return fmt.Errorf("删除文档时出错: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	vectors := kb.vectors[:0]
+	for _, v := range kb.vectors {
+		if !deleted[v.id] {
+			vectors = append(vectors, v)
+		}
+	}
+	kb.vectors = vectors
+	return nil
+}
+
+func (kb *LocalKB) ListDocuments() ([]Document, error) {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.listAll()
+}
+
+func (kb *LocalKB) listAll() ([]Document, error) {
+	rows, err := kb.db.Query(`SELECT id, text, metadata FROM kb_documents WHERE collection = ? ORDER BY rowid`, kb.collectionName)
+	if err != nil {
+		log.Printf("列出文档时出错: %v", err)
+		return nil, fmt.Errorf("列出文档时出错: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var doc Document
+		var metadata sql.NullString
+		if err := rows.Scan(&doc.ID, &doc.Text, &metadata); err != nil {
+			return nil, fmt.Errorf("扫描行失败: %v", err)
+		}
+		doc.Metadata = decodeMetadata(metadata)
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// ReEmbed 用当前向量模型重新生成集合中全部文档的向量
+func (kb *LocalKB) ReEmbed() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	ctx := context.Background()
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	docs, err := kb.listAll()
+	if err != nil {
+		return err
+	}
+	if err := kb.addBatches(ctx, docs); err != nil {
+		return err
+	}
+
+	if _, err := kb.db.Exec(`
+        INSERT OR REPLACE INTO kb_collections(name, embedding_model, embedding_dimension) VALUES(?, ?, ?)
+    `, kb.collectionName, kb.embeddingModel, dimension); err != nil {
+		return fmt.Errorf("更新集合信息失败: %v", err)
+	}
+	kb.mismatchErr = nil
+	log.Printf("集合 %s 使用 %s 重新向量化 %d 条文档", kb.collectionName, kb.embeddingModel, len(docs))
+	return kb.loadVectors()
+}
+
+func decodeMetadata(s sql.NullString) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if s.Valid && s.String != "" {
+		if err := json.Unmarshal([]byte(s.String), &metadata); err != nil {
+			log.Printf("解析元数据失败: %v", err)
+		}
+	}
+	return metadata
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// localStore 本地向量库的集合管理，全部集合保存在同一个数据库文件中
+type localStore struct {
+	conf *config.AppConfig
+	db   *sql.DB
+}
+
+func newLocalStore(conf *config.AppConfig) (*localStore, error) {
+	db, err := openLocalKBFile(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &localStore{conf: conf, db: db}, nil
+}
+
+func (s *localStore) open(name string) (KnowledgeBaseI, error) {
+	return newLocalKB(s.db, s.conf, name), nil
+}
+
+func (s *localStore) list() ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM kb_collections ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("列出集合失败: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("扫描行失败: %v", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *localStore) rename(oldName, newName string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE kb_collections SET name = ? WHERE name = ?`, newName, oldName); err != nil {
+		return fmt.Errorf("重命名集合失败: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE kb_documents SET collection = ? WHERE collection = ?`, newName, oldName); err != nil {
+		return fmt.Errorf("重命名集合失败: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (s *localStore) drop(name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM kb_documents WHERE collection = ?`, name); err != nil {
+		return fmt.Errorf("删除集合失败: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM kb_collections WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("删除集合失败: %v", err)
+	}
+	return tx.Commit()
+}
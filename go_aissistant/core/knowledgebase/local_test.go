@@ -0,0 +1,110 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+func newTestLocalKB(t *testing.T) *LocalKB {
+	t.Helper()
+	db, err := openLocalDB(filepath.Join(t.TempDir(), localKBFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	kb := newLocalKB(db, &config.AppConfig{EmbeddingModel: "letters"}, "kb")
+	kb.embedder = &letterEmbedder{}
+	if err := kb.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return kb
+}
+
+func TestLocalKBQuery(t *testing.T) {
+	kb := newTestLocalKB(t)
+
+	// 600 条文档，序号越大 b 越多，与查询 "a" 越不相近；相似度最低的三条来自 Rare.txt
+	var docs []Document
+	for i := 0; i < 600; i++ {
+		source, date, tags := "common.txt", "2024-01-01", "common"
+		if i >= 597 {
+			source, date, tags = "Rare.txt", "2024-06-0"+fmt.Sprint(i-596), "rare"
+		}
+		docs = append(docs, Document{
+			ID:       fmt.Sprintf("doc-%03d", i),
+			Text:     "a" + strings.Repeat("b", i),
+			Metadata: map[string]interface{}{"source": source, "date": date, MetaTags: tags},
+		})
+	}
+	if err := kb.AddDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(docs []Document) []string {
+		var ids []string
+		for _, d := range docs {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name string
+		opts QueryOptions
+		want []string
+	}{
+		{"不过滤", QueryOptions{NumResults: 2}, []string{"doc-000", "doc-001"}},
+		{"来源不区分大小写", QueryOptions{NumResults: 5, Sources: []string{" rare.txt"}}, []string{"doc-597", "doc-598", "doc-599"}},
+		{"标签不在 SQL 中过滤，需要读取多批候选", QueryOptions{NumResults: 5, Tags: []string{"rare"}}, []string{"doc-597", "doc-598", "doc-599"}},
+		{"非 ASCII 来源交给 Match", QueryOptions{NumResults: 5, Sources: []string{"Rare.txt", "说明.txt"}}, []string{"doc-597", "doc-598", "doc-599"}},
+		{"日期范围", QueryOptions{NumResults: 5, DateFrom: "2024-06-02", DateTo: "2024-06-30"}, []string{"doc-598", "doc-599"}},
+		{"没有匹配", QueryOptions{NumResults: 5, Sources: []string{"none.txt"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := kb.Query("a", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+			for _, d := range results {
+				if d.Score <= 0 || d.Text == "" || d.Metadata["source"] == nil {
+					t.Errorf("结果不完整: %+v", d)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalKBGetDocumentsAcrossBatches(t *testing.T) {
+	kb := newTestLocalKB(t)
+	var docs []Document
+	var ids []string
+	for i := 0; i < localFetchBatch+10; i++ {
+		id := fmt.Sprintf("doc-%03d", i)
+		docs = append(docs, Document{ID: id, Text: "a " + id})
+		ids = append([]string{id}, ids...) // 倒序请求
+	}
+	if err := kb.AddDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := kb.getDocuments(append(ids, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(ids) {
+		t.Fatalf("读到 %d 条，应为 %d", len(found), len(ids))
+	}
+	for i, d := range found {
+		if d.ID != ids[i] || d.Text != "a "+ids[i] {
+			t.Fatalf("第 %d 条为 %s，应按请求顺序返回 %s", i, d.ID, ids[i])
+		}
+	}
+}
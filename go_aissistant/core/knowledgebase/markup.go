@@ -0,0 +1,258 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// parseMarkdownSections 按标题把 Markdown 切分为片段，片段元数据中记录标题路径，如 "安装 > 使用 Docker"
+func parseMarkdownSections(path string) ([]Section, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text := []rune(string(content))
+	var sections []Section
+	for _, s := range splitMarkdownSections(text) {
+		section := Section{Title: s.heading, Text: string(text[s.start:s.end])}
+		if s.heading != "" {
+			section.Metadata = map[string]interface{}{MetaHeading: s.heading}
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// 与正文无关的页面元素，整棵子树跳过
+var htmlBoilerplate = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+	atom.Select:   true,
+}
+
+// 块级元素前后换段
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Blockquote: true, atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Details: true, atom.Summary: true, atom.Hr: true, atom.Br: true,
+}
+
+// parseHTMLSections 提取网页正文：去掉脚本、导航、页眉页脚等，优先取 <main> 或 <article>，
+// 标题转为 Markdown 标题，列表项以 "- " 开头，表格按行输出，链接保留文字
+func parseHTMLSections(path string) ([]Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := html.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("解析HTML失败: %v", err)
+	}
+
+	title, text := htmlDocumentText(doc)
+	section := Section{Title: title, Text: text}
+	if title != "" {
+		section.Metadata = map[string]interface{}{"title": title}
+	}
+	return []Section{section}, nil
+}
+
+// htmlDocumentText 返回页面标题和正文
+func htmlDocumentText(doc *html.Node) (string, string) {
+	title := ""
+	if n := findHTMLElement(doc, atom.Title); n != nil {
+		title = htmlInlineText(n, false)
+	}
+
+	root := findHTMLElement(doc, atom.Main)
+	if root == nil {
+		root = findHTMLElement(doc, atom.Article)
+	}
+	if root == nil {
+		root = findHTMLElement(doc, atom.Body)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	w := &blockWriter{}
+	w.walk(root)
+	return title, w.String()
+}
+
+func findHTMLElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findHTMLElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlInlineText 子树中的全部文字，空白折叠为单个空格；skipLists 为 true 时不含嵌套列表
+func htmlInlineText(n *html.Node, skipLists bool) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if htmlBoilerplate[n.DataAtom] || skipLists && (n.DataAtom == atom.Ul || n.DataAtom == atom.Ol) {
+				return
+			}
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collect(c)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// blockWriter 按块输出文本，块之间以空行分隔，相邻的列表项、表格行之间单个换行，
+// HTML、ODT 等格式共用
+type blockWriter struct {
+	out   strings.Builder
+	line  strings.Builder
+	tight bool // 上一个输出是列表项或表格行
+	lists int  // 当前列表嵌套层数
+}
+
+func (w *blockWriter) String() string {
+	w.flush()
+	return w.out.String()
+}
+
+func (w *blockWriter) emit(text string, tight bool) {
+	if text == "" {
+		return
+	}
+	if w.out.Len() > 0 {
+		if tight && w.tight {
+			w.out.WriteString("\n")
+		} else {
+			w.out.WriteString("\n\n")
+		}
+	}
+	w.out.WriteString(text)
+	w.tight = tight
+}
+
+// flush 输出当前累积的行内文字
+func (w *blockWriter) flush() {
+	text := strings.Join(strings.Fields(w.line.String()), " ")
+	w.line.Reset()
+	w.emit(text, false)
+}
+
+func (w *blockWriter) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+func (w *blockWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.line.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.walkChildren(n)
+		return
+	}
+
+	if htmlBoilerplate[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.flush()
+		level := int(n.Data[1] - '0')
+		if text := htmlInlineText(n, false); text != "" {
+			w.emit(strings.Repeat("#", level)+" "+text, false)
+		}
+	case atom.Ul, atom.Ol:
+		w.flush()
+		w.lists++
+		w.walkChildren(n)
+		w.lists--
+	case atom.Li:
+		w.flush()
+		// 嵌套列表单独输出，缩进体现层级
+		indent := strings.Repeat("  ", max(w.lists-1, 0))
+		if text := htmlInlineText(n, true); text != "" {
+			w.emit(indent+"- "+text, true)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				w.walk(c)
+			}
+		}
+	case atom.Table:
+		w.flush()
+		w.tight = false
+		w.walkChildren(n)
+		w.tight = false
+	case atom.Tr:
+		var cells []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+				cells = append(cells, htmlInlineText(c, false))
+			}
+		}
+		w.emit(strings.Join(cells, " | "), true)
+	case atom.Pre:
+		// 代码块保留原有换行和缩进
+		w.flush()
+		var b strings.Builder
+		collectHTMLText(n, &b)
+		if text := strings.Trim(b.String(), "\n"); strings.TrimSpace(text) != "" {
+			w.emit(text, false)
+		}
+	default:
+		block := htmlBlocks[n.DataAtom]
+		if block {
+			w.flush()
+		}
+		w.walkChildren(n)
+		if block {
+			w.flush()
+		}
+	}
+}
+
+func collectHTMLText(n *html.Node, b *strings.Builder) {
+	if n.Type == html.TextNode {
+		b.WriteString(n.Data)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectHTMLText(c, b)
+	}
+}
@@ -0,0 +1,676 @@
+package knowledgebase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+)
+
+const (
+	defaultMilvusURL = "http://localhost:19530"
+
+	// Milvus 集合字段
+	milvusFieldID       = "id"
+	milvusFieldText     = "text"
+	milvusFieldMetadata = "metadata"
+	milvusFieldVector   = "vector"
+
+	milvusMaxIDLength   = 512
+	milvusMaxTextLength = 65535
+	// 列出文档时每页的条数，按主键游标翻页，不受 query 接口 offset+limit 不超过 16384 的限制
+	milvusPageSize = 1000
+
+	// 重新向量化时临时集合名的后缀
+	milvusReembedSuffix = "__reembed"
+)
+
+// MilvusKB 通过 Milvus RESTful API (v2) 访问集合，适用于团队共享的 Milvus 服务。
+// 集合包含 id、text、metadata(JSON) 和 vector 四个字段，向量使用 COSINE 度量，
+// 集合描述中记录生成向量的模型名称，用于检测模型变更。
+type MilvusKB struct {
+	baseURL        string
+	token          string
+	collectionName string
+	httpClient     *http.Client
+	embedder       Embedder
+
+	embeddingModel     string
+	embeddingDimension int
+	batchSize          int
+
+	mu          sync.RWMutex
+	mismatchErr error
+}
+
+// NewMilvusKB milvus_url 形如 localhost:19530 或 http://milvus:19530，
+// 开启鉴权时通过 milvus_token 传入 "用户名:密码" 或 API Key
+func NewMilvusKB(conf *config.AppConfig) (*MilvusKB, error) {
+	baseURL := conf.MilvusURL
+	if baseURL == "" {
+		baseURL = defaultMilvusURL
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+
+	embeddingModel := conf.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = defaultEmbeddingModel
+	}
+	batchSize := conf.EmbeddingBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+
+	return &MilvusKB{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		token:          conf.MilvusToken,
+		collectionName: conf.CollectionName,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		embedder:           ai_model.NewOllamaClient(conf.OllamaURL),
+		embeddingModel:     embeddingModel,
+		embeddingDimension: conf.EmbeddingDimension,
+		batchSize:          batchSize,
+	}, nil
+}
+
+// milvusResponse RESTful API 的统一响应格式，code 为 0 表示成功
+type milvusResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// call 调用 /v2/vectordb 下的接口，out 为 nil 时忽略 data
+func (kb *MilvusKB) call(ctx context.Context, path string, body interface{}, out interface{}) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, kb.baseURL+"/v2/vectordb"+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if kb.token != "" {
+		req.Header.Set("Authorization", "Bearer "+kb.token)
+	}
+
+	resp, err := kb.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Milvus请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Milvus返回错误: %s (%d)", string(body), resp.StatusCode)
+	}
+
+	var result milvusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("Milvus返回错误: %s (%d)", result.Message, result.Code)
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// Name 集合名称
+func (kb *MilvusKB) Name() string {
+	return kb.collectionName
+}
+
+// Initialize 集合不存在时按当前向量模型的维度创建，存在时检查模型和维度并加载到内存
+func (kb *MilvusKB) Initialize() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	ctx := context.Background()
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	var has struct {
+		Has bool `json:"has"`
+	}
+	if err := kb.call(ctx, "/collections/has", map[string]interface{}{
+		"collectionName": kb.collectionName,
+	}, &has); err != nil {
+		return fmt.Errorf("检查集合失败: %w", err)
+	}
+	if !has.Has {
+		if err := kb.createCollection(ctx, kb.collectionName, dimension); err != nil {
+			return err
+		}
+		kb.mismatchErr = nil
+		return nil
+	}
+
+	if err := kb.checkCollection(ctx, dimension); err != nil {
+		kb.mismatchErr = err
+		log.Printf("%v", err)
+		return err
+	}
+	return kb.call(ctx, "/collections/load", map[string]interface{}{
+		"collectionName": kb.collectionName,
+	}, nil)
+}
+
+func (kb *MilvusKB) probeDimension(ctx context.Context) (int, error) {
+	vectors, err := kb.embed(ctx, []string{"dimension probe"})
+	if err != nil {
+		return 0, err
+	}
+	dimension := len(vectors[0])
+	if kb.embeddingDimension > 0 && kb.embeddingDimension != dimension {
+		return 0, fmt.Errorf("配置的向量维度 %d 与模型 %s 实际维度 %d 不一致", kb.embeddingDimension, kb.embeddingModel, dimension)
+	}
+	return dimension, nil
+}
+
+func (kb *MilvusKB) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, localEmbedTimeout)
+	defer cancel()
+	vectors, err := kb.embedder.Embed(ctx, kb.embeddingModel, texts)
+	if err != nil {
+		log.Printf("调用向量模型 %s 失败: %v", kb.embeddingModel, err)
+		return nil, fmt.Errorf("调用向量模型 %s 失败: %w", kb.embeddingModel, err)
+	}
+	return vectors, nil
+}
+
+// checkCollection 比较集合描述中记录的向量模型和向量字段的维度
+func (kb *MilvusKB) checkCollection(ctx context.Context, dimension int) error {
+	var desc struct {
+		Description string `json:"description"`
+		Fields      []struct {
+			Name   string `json:"name"`
+			Params []struct {
+				Key   string      `json:"key"`
+				Value interface{} `json:"value"`
+			} `json:"params"`
+		} `json:"fields"`
+	}
+	if err := kb.call(ctx, "/collections/describe", map[string]interface{}{
+		"collectionName": kb.collectionName,
+	}, &desc); err != nil {
+		return fmt.Errorf("读取集合信息失败: %w", err)
+	}
+
+	// 非本程序创建的集合没有模型记录，只校验维度
+	prefix := metaEmbeddingModel + "="
+	if model := strings.TrimPrefix(desc.Description, prefix); strings.HasPrefix(desc.Description, prefix) && model != kb.embeddingModel {
+		return fmt.Errorf("%w: 集合 %s 使用 %s，当前配置为 %s", ErrEmbeddingMismatch, kb.collectionName, model, kb.embeddingModel)
+	}
+	for _, field := range desc.Fields {
+		if field.Name != milvusFieldVector {
+			continue
+		}
+		for _, p := range field.Params {
+			if p.Key != "dim" {
+				continue
+			}
+			existing, _ := strconv.Atoi(fmt.Sprint(p.Value))
+			if existing != dimension {
+				return fmt.Errorf("%w: 集合 %s 的向量维度为 %d，当前模型 %s 为 %d", ErrEmbeddingMismatch, kb.collectionName, existing, kb.embeddingModel, dimension)
+			}
+		}
+	}
+	return nil
+}
+
+func (kb *MilvusKB) createCollection(ctx context.Context, name string, dimension int) error {
+	schema := map[string]interface{}{
+		"autoId":             false,
+		"enableDynamicField": false,
+		"fields": []map[string]interface{}{
+			{
+				"fieldName":         milvusFieldID,
+				"dataType":          "VarChar",
+				"isPrimary":         true,
+				"elementTypeParams": map[string]interface{}{"max_length": milvusMaxIDLength},
+			},
+			{
+				"fieldName":         milvusFieldText,
+				"dataType":          "VarChar",
+				"elementTypeParams": map[string]interface{}{"max_length": milvusMaxTextLength},
+			},
+			{
+				"fieldName": milvusFieldMetadata,
+				"dataType":  "JSON",
+			},
+			{
+				"fieldName":         milvusFieldVector,
+				"dataType":          "FloatVector",
+				"elementTypeParams": map[string]interface{}{"dim": dimension},
+			},
+		},
+	}
+	indexParams := []map[string]interface{}{
+		{
+			"fieldName":  milvusFieldVector,
+			"indexName":  milvusFieldVector,
+			"metricType": "COSINE",
+			"indexType":  "AUTOINDEX",
+		},
+	}
+
+	// 带索引参数创建的集合会自动加载
+	if err := kb.call(ctx, "/collections/create", map[string]interface{}{
+		"collectionName": name,
+		"description":    metaEmbeddingModel + "=" + kb.embeddingModel,
+		"schema":         schema,
+		"indexParams":    indexParams,
+	}, nil); err != nil {
+		log.Printf("创建集合失败: %v", err)
+		return fmt.Errorf("创建集合失败: %w", err)
+	}
+	log.Printf("创建Milvus集合 %s，向量模型 %s，维度 %d", name, kb.embeddingModel, dimension)
+	return nil
+}
+
+// AddDocuments 分批生成向量后写入，ID 相同的文档会被覆盖
+func (kb *MilvusKB) AddDocuments(docs []Document) error {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+
+	if kb.mismatchErr != nil {
+		return kb.mismatchErr
+	}
+	return kb.addBatches(context.Background(), kb.collectionName, docs)
+}
+
+// addBatches 分批生成向量并写入 collection
+func (kb *MilvusKB) addBatches(ctx context.Context, collection string, docs []Document) error {
+	for start := 0; start < len(docs); start += kb.batchSize {
+		end := start + kb.batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch := docs[start:end]
+
+		texts := make([]string, len(batch))
+		for i, doc := range batch {
+			texts[i] = doc.Text
+		}
+		vectors, err := kb.embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+
+		rows := make([]map[string]interface{}, len(batch))
+		for i, doc := range batch {
+			id := doc.ID
+			if id == "" {
+				id = fmt.Sprintf("doc-%d-%d", time.Now().UnixNano(), i)
+			}
+			metadata := doc.Metadata
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+			}
+			rows[i] = map[string]interface{}{
+				milvusFieldID:       id,
+				milvusFieldText:     doc.Text,
+				milvusFieldMetadata: metadata,
+				milvusFieldVector:   vectors[i],
+			}
+		}
+
+		if err := kb.call(ctx, "/entities/upsert", map[string]interface{}{
+			"collectionName": collection,
+			"data":           rows,
+		}, nil); err != nil {
+			log.Printf("写入文档失败: %v", err)
+			return fmt.Errorf("写入文档失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// milvusEntity search/query 返回的一行，metadata 可能以 JSON 对象或 JSON 字符串返回
+type milvusEntity struct {
+	ID       string          `json:"id"`
+	Text     string          `json:"text"`
+	Metadata json.RawMessage `json:"metadata"`
+	Distance float32         `json:"distance"`
+}
+
+func (e milvusEntity) document() Document {
+	metadata := map[string]interface{}{}
+	raw := e.Metadata
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		raw = json.RawMessage(s)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			log.Printf("解析元数据失败: %v", err)
+		}
+	}
+	return Document{ID: e.ID, Text: e.Text, Metadata: metadata}
+}
+
+// Query 来源和日期条件转为 Milvus 过滤表达式，其余条件在多取的候选中筛选；COSINE 度量返回的 distance 即相似度
+func (kb *MilvusKB) Query(query string, opts QueryOptions) ([]Document, error) {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+
+	if kb.mismatchErr != nil {
+		return nil, kb.mismatchErr
+	}
+
+	ctx := context.Background()
+	vectors, err := kb.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"collectionName": kb.collectionName,
+		"data":           [][]float32{vectors[0]},
+		"annsField":      milvusFieldVector,
+		"limit":          opts.fetchSize(),
+		"outputFields":   []string{milvusFieldID, milvusFieldText, milvusFieldMetadata},
+	}
+	if filter := milvusFilter(opts); filter != "" {
+		body["filter"] = filter
+	}
+	var entities []milvusEntity
+	if err := kb.call(ctx, "/entities/search", body, &entities); err != nil {
+		log.Printf("查询集合时出错: %v", err)
+		return nil, fmt.Errorf("查询集合时出错: %w", err)
+	}
+
+	docs := make([]Document, len(entities))
+	for i, e := range entities {
+		docs[i] = e.document()
+		docs[i].Score = e.Distance
+	}
+	return opts.filterDocuments(docs), nil
+}
+
+// milvusFilter 把来源和日期条件转为 JSON 字段 metadata 上的过滤表达式
+func milvusFilter(opts QueryOptions) string {
+	var conds []string
+	if len(opts.Sources) > 0 {
+		quoted := make([]string, len(opts.Sources))
+		for i, s := range opts.Sources {
+			quoted[i] = strconv.Quote(s)
+		}
+		conds = append(conds, fmt.Sprintf(`%s["source"] in [%s]`, milvusFieldMetadata, strings.Join(quoted, ", ")))
+	}
+	if opts.DateFrom != "" {
+		conds = append(conds, fmt.Sprintf(`%s["date"] >= %s`, milvusFieldMetadata, strconv.Quote(opts.DateFrom)))
+	}
+	if opts.DateTo != "" {
+		conds = append(conds, fmt.Sprintf(`%s["date"] <= %s`, milvusFieldMetadata, strconv.Quote(opts.DateTo)))
+	}
+	return strings.Join(conds, " and ")
+}
+
+func (kb *MilvusKB) DeleteDocument(id string) error {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+
+	ctx := context.Background()
+	filter := fmt.Sprintf("%s == %s", milvusFieldID, strconv.Quote(id))
+
+	var found []milvusEntity
+	if err := kb.call(ctx, "/entities/query", map[string]interface{}{
+		"collectionName": kb.collectionName,
+		"filter":         filter,
+		"outputFields":   []string{milvusFieldID},
+		"limit":          1,
+	}, &found); err != nil {
+		return fmt.Errorf("删除文档时出错: %w", err)
+	}
+	if len(found) == 0 {
+		log.Printf("未找到要删除的文档: %s", id)
+		return fmt.Errorf("未找到要删除的文档: %s", id)
+	}
+
+	if err := kb.call(ctx, "/entities/delete", map[string]interface{}{
+		"collectionName": kb.collectionName,
+		"filter":         filter,
+	}, nil); err != nil {
+		log.Printf("删除文档时出错: %v", err)
+		return fmt.Errorf("删除文档时出错: %w", err)
+	}
+	return nil
+}
+
+// DeleteBySource 按 JSON 字段 metadata 中的 path 删除文档
+func (kb *MilvusKB) DeleteBySource(path string, keep ...string) error {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+
+	filter := fmt.Sprintf(`%s["%s"] == %s`, milvusFieldMetadata, MetaPath, strconv.Quote(path))
+	if len(keep) > 0 {
+		quoted := make([]string, len(keep))
+		for i, id := range keep {
+			quoted[i] = strconv.Quote(id)
+		}
+		filter += fmt.Sprintf(` and %s not in [%s]`, milvusFieldID, strings.Join(quoted, ", "))
+	}
+	if err := kb.call(context.Background(), "/entities/delete", map[string]interface{}{
+		"collectionName": kb.collectionName,
+		"filter":         filter,
+	}, nil); err != nil {
+		log.Printf("删除文档时出错: %v", err)
+		return fmt.Errorf("删除文档时出错: %w", err)
+	}
+	return nil
+}
+
+func (kb *MilvusKB) ListDocuments() ([]Document, error) {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.listAll(context.Background())
+}
+
+// listAfter 按主键顺序列出 ID 大于 after 的最多 limit 条文档。
+// 以主键为游标翻页，与 pymilvus 的 QueryIterator 相同，不受 offset+limit 的窗口限制
+func (kb *MilvusKB) listAfter(ctx context.Context, collection, after string, limit int) ([]Document, error) {
+	var entities []milvusEntity
+	if err := kb.call(ctx, "/entities/query", map[string]interface{}{
+		"collectionName": collection,
+		"filter":         fmt.Sprintf(`%s > %s`, milvusFieldID, strconv.Quote(after)),
+		"outputFields":   []string{milvusFieldID, milvusFieldText, milvusFieldMetadata},
+		"limit":          limit,
+	}, &entities); err != nil {
+		log.Printf("列出文档时出错: %v", err)
+		return nil, fmt.Errorf("列出文档时出错: %w", err)
+	}
+
+	docs := make([]Document, len(entities))
+	for i, e := range entities {
+		docs[i] = e.document()
+	}
+	return docs, nil
+}
+
+// eachPage 逐页读取集合中的全部文档
+func (kb *MilvusKB) eachPage(ctx context.Context, fn func(page []Document) error) error {
+	cursor := ""
+	for {
+		page, err := kb.listAfter(ctx, kb.collectionName, cursor, milvusPageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		// 依赖 Milvus 按主键顺序返回结果（pymilvus 的 QueryIterator 同样如此），本页最后一条的主键即下一页的游标；
+		// 结果未按主键排序时，无论取哪个主键作为游标都可能漏读或重复读取
+		cursor = page[len(page)-1].ID
+		if len(page) < milvusPageSize {
+			return nil
+		}
+	}
+}
+
+func (kb *MilvusKB) listAll(ctx context.Context) ([]Document, error) {
+	var docs []Document
+	err := kb.eachPage(ctx, func(page []Document) error {
+		docs = append(docs, page...)
+		return nil
+	})
+	return docs, err
+}
+
+// ReEmbed 逐页读出文档，用当前向量模型写入临时集合，全部写入成功后再删除旧集合并把临时集合改为原名，
+// 文档 ID 保持不变。向量化或写入失败时删除临时集合，旧集合不受影响
+func (kb *MilvusKB) ReEmbed() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	ctx := context.Background()
+	dimension, err := kb.probeDimension(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 模型不一致时 Initialize 没有加载集合，读取前先加载
+	if err := kb.call(ctx, "/collections/load", map[string]interface{}{
+		"collectionName": kb.collectionName,
+	}, nil); err != nil {
+		return fmt.Errorf("加载集合失败: %w", err)
+	}
+
+	tmpName := kb.collectionName + milvusReembedSuffix
+	if err := kb.dropCollection(ctx, tmpName); err != nil {
+		return err
+	}
+	if err := kb.createCollection(ctx, tmpName, dimension); err != nil {
+		return err
+	}
+	count := 0
+	err = kb.eachPage(ctx, func(page []Document) error {
+		count += len(page)
+		return kb.addBatches(ctx, tmpName, page)
+	})
+	if err != nil {
+		if dropErr := kb.dropCollection(ctx, tmpName); dropErr != nil {
+			log.Printf("删除临时集合 %s 时出错: %v", tmpName, dropErr)
+		}
+		return fmt.Errorf("重新向量化失败，原集合未改动: %w", err)
+	}
+
+	if err := kb.dropCollection(ctx, kb.collectionName); err != nil {
+		return fmt.Errorf("%v，新向量保存在临时集合 %s 中", err, tmpName)
+	}
+	if err := kb.call(ctx, "/collections/rename", map[string]interface{}{
+		"collectionName":    tmpName,
+		"newCollectionName": kb.collectionName,
+	}, nil); err != nil {
+		log.Printf("重命名集合失败: %v", err)
+		return fmt.Errorf("重命名临时集合 %s 失败，请手动改名为 %s: %w", tmpName, kb.collectionName, err)
+	}
+	if err := kb.call(ctx, "/collections/load", map[string]interface{}{
+		"collectionName": kb.collectionName,
+	}, nil); err != nil {
+		return fmt.Errorf("加载集合失败: %w", err)
+	}
+	kb.mismatchErr = nil
+	log.Printf("集合 %s 使用 %s 重新向量化 %d 条文档", kb.collectionName, kb.embeddingModel, count)
+	return nil
+}
+
+// dropCollection 删除集合，集合不存在时不报错
+func (kb *MilvusKB) dropCollection(ctx context.Context, name string) error {
+	var has struct {
+		Has bool `json:"has"`
+	}
+	if err := kb.call(ctx, "/collections/has", map[string]interface{}{
+		"collectionName": name,
+	}, &has); err != nil {
+		return fmt.Errorf("检查集合失败: %w", err)
+	}
+	if !has.Has {
+		return nil
+	}
+	if err := kb.call(ctx, "/collections/drop", map[string]interface{}{
+		"collectionName": name,
+	}, nil); err != nil {
+		return fmt.Errorf("删除集合失败: %w", err)
+	}
+	return nil
+}
+
+// milvusStore Milvus 服务上的集合管理
+type milvusStore struct {
+	conf   *config.AppConfig
+	client *MilvusKB
+}
+
+func newMilvusStore(conf *config.AppConfig) (*milvusStore, error) {
+	kb, err := NewMilvusKB(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &milvusStore{conf: conf, client: kb}, nil
+}
+
+func (s *milvusStore) open(name string) (KnowledgeBaseI, error) {
+	conf := *s.conf
+	conf.CollectionName = name
+	return NewMilvusKB(&conf)
+}
+
+func (s *milvusStore) list() ([]string, error) {
+	var names []string
+	if err := s.client.call(context.Background(), "/collections/list", map[string]interface{}{}, &names); err != nil {
+		return nil, fmt.Errorf("列出集合时出错: %w", err)
+	}
+	visible := names[:0]
+	for _, name := range names {
+		if !strings.HasSuffix(name, milvusReembedSuffix) {
+			visible = append(visible, name)
+		}
+	}
+	return visible, nil
+}
+
+func (s *milvusStore) rename(oldName, newName string) error {
+	if err := s.client.call(context.Background(), "/collections/rename", map[string]interface{}{
+		"collectionName":    oldName,
+		"newCollectionName": newName,
+	}, nil); err != nil {
+		return fmt.Errorf("重命名集合失败: %w", err)
+	}
+	return nil
+}
+
+func (s *milvusStore) drop(name string) error {
+	if err := s.client.call(context.Background(), "/collections/drop", map[string]interface{}{
+		"collectionName": name,
+	}, nil); err != nil {
+		return fmt.Errorf("删除集合失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package knowledgebase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+// fakeMilvus 内存中的 Milvus RESTful API (v2)，只实现 MilvusKB 用到的接口和过滤表达式
+type fakeMilvus struct {
+	mu          sync.Mutex
+	collections map[string]*fakeCollection
+}
+
+type fakeCollection struct {
+	description string
+	dim         int
+	rows        map[string]fakeRow
+}
+
+type fakeRow struct {
+	text     string
+	metadata map[string]interface{}
+	vector   []float64
+}
+
+func newFakeMilvus(t *testing.T) (*fakeMilvus, *httptest.Server) {
+	f := &fakeMilvus{collections: map[string]*fakeCollection{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeMilvus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	data, err := f.handle(strings.TrimPrefix(r.URL.Path, "/v2/vectordb"), req)
+	f.mu.Unlock()
+
+	resp := map[string]interface{}{"code": 0, "data": data}
+	if err != nil {
+		resp = map[string]interface{}{"code": 1100, "message": err.Error()}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeMilvus) handle(path string, req map[string]interface{}) (interface{}, error) {
+	name, _ := req["collectionName"].(string)
+	c := f.collections[name]
+	if c == nil && path != "/collections/has" && path != "/collections/create" && path != "/collections/list" {
+		return nil, fmt.Errorf("collection not found[collection=%s]", name)
+	}
+
+	switch path {
+	case "/collections/has":
+		return map[string]bool{"has": c != nil}, nil
+	case "/collections/create":
+		if c != nil {
+			return nil, fmt.Errorf("collection already exists: %s", name)
+		}
+		c = &fakeCollection{rows: map[string]fakeRow{}}
+		c.description, _ = req["description"].(string)
+		schema := req["schema"].(map[string]interface{})
+		for _, field := range schema["fields"].([]interface{}) {
+			field := field.(map[string]interface{})
+			if params, ok := field["elementTypeParams"].(map[string]interface{}); ok && field["fieldName"] == milvusFieldVector {
+				c.dim = int(params["dim"].(float64))
+			}
+		}
+		f.collections[name] = c
+		return nil, nil
+	case "/collections/describe":
+		return map[string]interface{}{
+			"description": c.description,
+			"fields": []map[string]interface{}{
+				{"name": milvusFieldID},
+				{"name": milvusFieldVector, "params": []map[string]interface{}{{"key": "dim", "value": strconv.Itoa(c.dim)}}},
+			},
+		}, nil
+	case "/collections/load":
+		return nil, nil
+	case "/collections/list":
+		names := []string{}
+		for n := range f.collections {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return names, nil
+	case "/collections/drop":
+		delete(f.collections, name)
+		return nil, nil
+	case "/collections/rename":
+		newName := req["newCollectionName"].(string)
+		if f.collections[newName] != nil {
+			return nil, fmt.Errorf("duplicated new collection name: %s", newName)
+		}
+		f.collections[newName] = c
+		delete(f.collections, name)
+		return nil, nil
+	case "/entities/upsert":
+		for _, item := range req["data"].([]interface{}) {
+			row := item.(map[string]interface{})
+			var vector []float64
+			for _, x := range row[milvusFieldVector].([]interface{}) {
+				vector = append(vector, x.(float64))
+			}
+			if len(vector) != c.dim {
+				return nil, fmt.Errorf("the dim (%d) of field data(vector) is not equal to schema dim (%d)", len(vector), c.dim)
+			}
+			c.rows[row[milvusFieldID].(string)] = fakeRow{
+				text:     row[milvusFieldText].(string),
+				metadata: row[milvusFieldMetadata].(map[string]interface{}),
+				vector:   vector,
+			}
+		}
+		return nil, nil
+	case "/entities/query":
+		offset, _ := req["offset"].(float64)
+		limit, _ := req["limit"].(float64)
+		if offset+limit > 16384 {
+			return nil, fmt.Errorf("invalid max query result window, (offset+limit) should be in range [1, 16384]")
+		}
+		filter, _ := req["filter"].(string)
+		var result []map[string]interface{}
+		for _, id := range c.sortedIDs() {
+			if fakeMatch(filter, id, c.rows[id]) {
+				result = append(result, c.rows[id].entity(id))
+			}
+		}
+		result = result[min(int(offset), len(result)):]
+		return result[:min(int(limit), len(result))], nil
+	case "/entities/search":
+		filter, _ := req["filter"].(string)
+		limit := int(req["limit"].(float64))
+		var query []float64
+		for _, x := range req["data"].([]interface{})[0].([]interface{}) {
+			query = append(query, x.(float64))
+		}
+		var result []map[string]interface{}
+		for _, id := range c.sortedIDs() {
+			if row := c.rows[id]; fakeMatch(filter, id, row) {
+				e := row.entity(id)
+				e["distance"] = fakeCosine(query, row.vector)
+				result = append(result, e)
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i]["distance"].(float64) > result[j]["distance"].(float64)
+		})
+		return result[:min(limit, len(result))], nil
+	case "/entities/delete":
+		filter, _ := req["filter"].(string)
+		for id, row := range c.rows {
+			if fakeMatch(filter, id, row) {
+				delete(c.rows, id)
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported path %s", path)
+}
+
+func (c *fakeCollection) sortedIDs() []string {
+	ids := make([]string, 0, len(c.rows))
+	for id := range c.rows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (r fakeRow) entity(id string) map[string]interface{} {
+	return map[string]interface{}{milvusFieldID: id, milvusFieldText: r.text, milvusFieldMetadata: r.metadata}
+}
+
+var fakeCondPattern = regexp.MustCompile(`^(id|metadata\["(\w+)"\]) (==|!=|>|>=|<=|in|not in) (.+)$`)
+
+// fakeMatch 支持 MilvusKB 生成的表达式：id 或 metadata["key"] 与字符串比较、in 和 not in 列表，多个条件用 and 连接
+func fakeMatch(filter, id string, row fakeRow) bool {
+	if filter == "" {
+		return true
+	}
+	for _, cond := range strings.Split(filter, " and ") {
+		m := fakeCondPattern.FindStringSubmatch(cond)
+		if m == nil {
+			panic("unsupported filter: " + cond)
+		}
+		field := id
+		if m[2] != "" {
+			field = fmt.Sprint(row.metadata[m[2]])
+		}
+		var ok bool
+		if m[3] == "in" || m[3] == "not in" {
+			var values []string
+			if err := json.Unmarshal([]byte(m[4]), &values); err != nil {
+				panic(err)
+			}
+			for _, v := range values {
+				ok = ok || v == field
+			}
+			ok = ok == (m[3] == "in")
+		} else {
+			value, err := strconv.Unquote(m[4])
+			if err != nil {
+				panic(err)
+			}
+			switch m[3] {
+			case "==":
+				ok = field == value
+			case "!=":
+				ok = field != value
+			case ">":
+				ok = field > value
+			case ">=":
+				ok = field >= value
+			case "<=":
+				ok = field <= value
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func fakeCosine(a, b []float64) float64 {
+	var dotProduct, na, nb float64
+	for i := range a {
+		dotProduct += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dotProduct / math.Sqrt(na*nb)
+}
+
+// letterEmbedder 以 a、b、c 的出现次数加一个常数分量作为向量；calls 达到 failAfter 后返回错误
+type letterEmbedder struct {
+	mu        sync.Mutex
+	calls     int
+	failAfter int
+}
+
+func (e *letterEmbedder) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	if e.failAfter > 0 && e.calls > e.failAfter {
+		return nil, errors.New("connection refused")
+	}
+	vectors := make([][]float32, len(input))
+	for i, text := range input {
+		vectors[i] = []float32{
+			float32(strings.Count(text, "a")),
+			float32(strings.Count(text, "b")),
+			float32(strings.Count(text, "c")),
+			1,
+		}
+	}
+	return vectors, nil
+}
+
+func newTestMilvusKB(t *testing.T, url string, embedder Embedder) *MilvusKB {
+	kb, err := NewMilvusKB(&config.AppConfig{MilvusURL: url, CollectionName: "kb", EmbeddingModel: "letters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb.embedder = embedder
+	return kb
+}
+
+// seed 直接向假服务写入 n 条旧模型生成的 3 维向量
+func (f *fakeMilvus) seed(name, model string, n int) {
+	c := &fakeCollection{description: metaEmbeddingModel + "=" + model, dim: 3, rows: map[string]fakeRow{}}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("doc-%05d", i)
+		c.rows[id] = fakeRow{text: "text " + id, metadata: map[string]interface{}{MetaPath: "/data/" + id}, vector: []float64{1, 0, 0}}
+	}
+	f.collections[name] = c
+}
+
+func TestMilvusKB(t *testing.T) {
+	f, srv := newFakeMilvus(t)
+	kb := newTestMilvusKB(t, srv.URL, &letterEmbedder{})
+	if err := kb.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.collections["kb"]; c == nil || c.dim != 4 || c.description != metaEmbeddingModel+"=letters" {
+		t.Fatalf("集合未按模型维度创建: %+v", c)
+	}
+
+	docs := []Document{
+		{ID: "a", Text: "aaaa", Metadata: map[string]interface{}{"source": "a.txt", MetaPath: "/a.txt"}},
+		{ID: "b", Text: "bbbb", Metadata: map[string]interface{}{"source": "b.txt", MetaPath: "/b.txt"}},
+		{ID: "c1", Text: "cccc", Metadata: map[string]interface{}{"source": "c.txt", MetaPath: "/c.txt"}},
+		{ID: "c2", Text: "ccc a", Metadata: map[string]interface{}{"source": "c.txt", MetaPath: "/c.txt"}},
+	}
+	if err := kb.AddDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+	// 相同 ID 覆盖
+	if err := kb.AddDocuments([]Document{{ID: "b", Text: "bbb", Metadata: map[string]interface{}{"source": "b.txt"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.collections["kb"].rows); n != 4 {
+		t.Fatalf("upsert 后有 %d 条文档，应为 4", n)
+	}
+
+	results, err := kb.Query("aa", QueryOptions{NumResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "a" || results[0].Text != "aaaa" || results[0].Metadata["source"] != "a.txt" {
+		t.Fatalf("检索结果不正确: %+v", results)
+	}
+	if results[0].Score < 0.9 || results[1].Score >= results[0].Score {
+		t.Errorf("相似度不正确: %v, %v", results[0].Score, results[1].Score)
+	}
+
+	results, err = kb.Query("aa", QueryOptions{NumResults: 2, Sources: []string{"c.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "c2" {
+		t.Errorf("来源过滤后的结果不正确: %+v", results)
+	}
+
+	if err := kb.DeleteDocument("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kb.DeleteDocument("a"); err == nil {
+		t.Error("删除不存在的文档应返回错误")
+	}
+	if err := kb.DeleteBySource("/c.txt", "c2"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := f.collections["kb"].rows; len(rows) != 2 || rows["c2"].text == "" {
+		t.Fatalf("按来源删除时应保留指定的文档: %v", rows)
+	}
+	if err := kb.DeleteBySource("/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	listed, err := kb.ListDocuments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != "b" || listed[0].Text != "bbb" {
+		t.Errorf("删除后剩余文档不正确: %+v", listed)
+	}
+}
+
+func TestMilvusListBeyondQueryWindow(t *testing.T) {
+	f, srv := newFakeMilvus(t)
+	f.seed("kb", "letters", 17000)
+	kb := newTestMilvusKB(t, srv.URL, &letterEmbedder{})
+
+	docs, err := kb.ListDocuments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 17000 {
+		t.Fatalf("列出 %d 条文档，应为 17000", len(docs))
+	}
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		seen[doc.ID] = true
+	}
+	if len(seen) != 17000 {
+		t.Errorf("列出的文档有重复，不同 ID 共 %d 个", len(seen))
+	}
+}
+
+func TestMilvusReEmbed(t *testing.T) {
+	f, srv := newFakeMilvus(t)
+	f.seed("kb", "old-model", 2500)
+	kb := newTestMilvusKB(t, srv.URL, &letterEmbedder{})
+
+	if err := kb.Initialize(); !errors.Is(err, ErrEmbeddingMismatch) {
+		t.Fatalf("模型不一致时应返回 ErrEmbeddingMismatch，得到 %v", err)
+	}
+	if _, err := kb.Query("a", QueryOptions{NumResults: 1}); !errors.Is(err, ErrEmbeddingMismatch) {
+		t.Fatalf("重新向量化前应拒绝检索，得到 %v", err)
+	}
+
+	if err := kb.ReEmbed(); err != nil {
+		t.Fatal(err)
+	}
+	c := f.collections["kb"]
+	if c == nil || c.dim != 4 || c.description != metaEmbeddingModel+"=letters" || len(c.rows) != 2500 {
+		t.Fatalf("重新向量化后的集合不正确: dim=%d description=%q rows=%d", c.dim, c.description, len(c.rows))
+	}
+	if row := c.rows["doc-02499"]; row.text != "text doc-02499" || row.metadata[MetaPath] != "/data/doc-02499" {
+		t.Errorf("文档内容或元数据丢失: %+v", row)
+	}
+	if _, ok := f.collections["kb"+milvusReembedSuffix]; ok {
+		t.Error("临时集合没有删除")
+	}
+	if _, err := kb.Query("a", QueryOptions{NumResults: 1}); err != nil {
+		t.Errorf("重新向量化后检索失败: %v", err)
+	}
+}
+
+func TestMilvusReEmbedFailureKeepsCollection(t *testing.T) {
+	f, srv := newFakeMilvus(t)
+	f.seed("kb", "old-model", 2500)
+	// 探测维度和前几批成功，之后向量模型不可用
+	kb := newTestMilvusKB(t, srv.URL, &letterEmbedder{failAfter: 5})
+
+	if err := kb.ReEmbed(); err == nil {
+		t.Fatal("向量模型失败时 ReEmbed 应返回错误")
+	}
+	c := f.collections["kb"]
+	if c == nil || c.dim != 3 || len(c.rows) != 2500 {
+		t.Fatalf("失败后原集合应保持不变: %+v", c)
+	}
+	if _, ok := f.collections["kb"+milvusReembedSuffix]; ok {
+		t.Error("失败后临时集合没有删除")
+	}
+}
+
+func TestMilvusStoreHidesTemporaryCollection(t *testing.T) {
+	f, srv := newFakeMilvus(t)
+	f.seed("kb", "letters", 1)
+	f.seed("kb"+milvusReembedSuffix, "letters", 1)
+
+	store, err := newMilvusStore(&config.AppConfig{MilvusURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := store.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "kb" {
+		t.Errorf("集合列表 = %v, want [kb]", names)
+	}
+}
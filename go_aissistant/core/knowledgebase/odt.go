@@ -0,0 +1,205 @@
+package knowledgebase
+
+import (
+	"archive/zip"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ODT 中不属于正文的元素：修订记录、批注、目录、变量声明等
+var odtSkipped = map[string]bool{
+	"tracked-changes":    true,
+	"annotation":         true,
+	"table-of-content":   true,
+	"sequence-decls":     true,
+	"variable-decls":     true,
+	"user-field-decls":   true,
+	"forms":              true,
+	"illustration-index": true,
+}
+
+// parseODTSections 解析 OpenDocument 文本：标题转为 Markdown 标题，列表项以 "- " 开头，表格按行输出；
+// 页眉页脚（styles.xml 中的母版页）和脚注、尾注作为附加片段（includeExtras 为 false 时忽略）
+func parseODTSections(path string, includeExtras bool) ([]Section, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开ODT文件失败: %v", err)
+	}
+	defer r.Close()
+
+	parts := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+
+	content, ok := parts["content.xml"]
+	if !ok {
+		return nil, fmt.Errorf("无效的ODT文件: 缺少 content.xml")
+	}
+	root, err := readDocxPart(content, parseXMLTree)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &odtWriter{notes: map[string][]string{}}
+	if body := root.find("body"); body != nil {
+		w.walk(body, 0)
+	}
+	sections := []Section{{Title: sectionBody, Text: w.String()}}
+	if !includeExtras {
+		return sections, nil
+	}
+
+	if f, ok := parts["styles.xml"]; ok {
+		styles, err := readDocxPart(f, parseXMLTree)
+		if err != nil {
+			return nil, err
+		}
+		// 页眉页脚按首页、左右页分别定义，合并时去重
+		for _, extra := range []struct {
+			title string
+			names []string
+		}{
+			{sectionHeader, []string{"header", "header-first", "header-left"}},
+			{sectionFooter, []string{"footer", "footer-first", "footer-left"}},
+		} {
+			var texts []string
+			seen := map[string]bool{}
+			for _, page := range styles.findAll("master-page") {
+				for _, name := range extra.names {
+					for _, n := range page.findAll(name) {
+						pw := &odtWriter{notes: map[string][]string{}}
+						pw.walk(n, 0)
+						if text := strings.TrimSpace(pw.String()); text != "" && !seen[text] {
+							seen[text] = true
+							texts = append(texts, text)
+						}
+					}
+				}
+			}
+			if len(texts) > 0 {
+				sections = append(sections, Section{Title: extra.title, Text: strings.Join(texts, "\n\n")})
+			}
+		}
+	}
+
+	for _, note := range []struct{ title, class string }{
+		{sectionFootnotes, "footnote"},
+		{sectionEndnotes, "endnote"},
+	} {
+		if texts := w.notes[note.class]; len(texts) > 0 {
+			sections = append(sections, Section{Title: note.title, Text: strings.Join(texts, "\n\n")})
+		}
+	}
+	return sections, nil
+}
+
+// odtWriter 按文档顺序输出段落，脚注、尾注正文按类别收集
+type odtWriter struct {
+	blockWriter
+	notes map[string][]string
+}
+
+func (w *odtWriter) walk(n *xmlNode, lists int) {
+	for _, c := range n.Children {
+		if c.isText() || odtSkipped[c.Name.Local] {
+			continue
+		}
+
+		switch c.Name.Local {
+		case "h":
+			level, err := strconv.Atoi(c.attr("outline-level"))
+			if err != nil || level < 1 {
+				level = 1
+			}
+			if text := w.inline(c); text != "" {
+				w.emit(strings.Repeat("#", min(level, 6))+" "+text, false)
+			}
+		case "p":
+			text := w.inline(c)
+			if lists > 0 && text != "" {
+				text = strings.Repeat("  ", lists-1) + "- " + text
+			}
+			w.emit(text, lists > 0)
+		case "list":
+			w.walk(c, lists+1)
+		case "table":
+			w.tight = false
+			for _, row := range c.findAll("table-row") {
+				var cells []string
+				for _, cell := range row.findAll("table-cell") {
+					cells = append(cells, w.inline(cell))
+				}
+				w.emit(strings.Join(cells, " | "), true)
+			}
+			w.tight = false
+		default:
+			w.walk(c, lists)
+		}
+	}
+}
+
+// inline 段落中的文字：连续空白折叠为一个空格，text:s、text:tab、text:line-break 还原为空格、制表符和换行，
+// 脚注引用处写入 "[编号]"
+func (w *odtWriter) inline(n *xmlNode) string {
+	var b strings.Builder
+	var collect func(*xmlNode)
+	collect = func(n *xmlNode) {
+		for _, c := range n.Children {
+			if c.isText() {
+				if fields := strings.Fields(c.Text); len(fields) > 0 {
+					if c.Text[0] == ' ' || c.Text[0] == '\n' || c.Text[0] == '\t' {
+						b.WriteString(" ")
+					}
+					b.WriteString(strings.Join(fields, " "))
+					if last := c.Text[len(c.Text)-1]; last == ' ' || last == '\n' || last == '\t' {
+						b.WriteString(" ")
+					}
+				}
+				continue
+			}
+			if odtSkipped[c.Name.Local] {
+				continue
+			}
+
+			switch c.Name.Local {
+			case "s":
+				count, err := strconv.Atoi(c.attr("c"))
+				if err != nil || count < 1 {
+					count = 1
+				}
+				b.WriteString(strings.Repeat(" ", count))
+			case "tab":
+				b.WriteString("\t")
+			case "line-break":
+				b.WriteString("\n")
+			case "note":
+				citation := ""
+				if cit := c.find("note-citation"); cit != nil {
+					citation = strings.TrimSpace(cit.text())
+				}
+				b.WriteString("[" + citation + "]")
+				if body := c.find("note-body"); body != nil {
+					var paras []string
+					for _, p := range body.findAll("p") {
+						if text := w.inline(p); text != "" {
+							paras = append(paras, text)
+						}
+					}
+					class := c.attr("note-class")
+					w.notes[class] = append(w.notes[class], "["+citation+"] "+strings.Join(paras, "\n"))
+				}
+			case "p", "h":
+				// 文本框中的段落
+				b.WriteString(" ")
+				collect(c)
+				b.WriteString(" ")
+			default:
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,242 @@
+package knowledgebase
+
+import (
+	"math"
+	"sort"
+)
+
+// 截断后剩余不足该 token 数的块不再放入上下文
+const minPackedTokens = 64
+
+// EstimateTokens 粗略估计文本的 token 数：中日韩文字约每字一个 token，其余字符约每 4 个一个 token。
+// 不同模型的分词不同，只用于控制上下文长度
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case isIdeographic(r):
+			cjk++
+		case r != ' ' && r != '\n' && r != '\t':
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// Diversify 按最大边际相关性（MMR）从候选中选出 k 条：每次选择 lambda*相关度 - (1-lambda)*与已选结果的最大相似度 最高的一条，
+// 避免几乎重复的块占满上下文。相关度取重排得分（未重排时取检索得分），相似度按词频向量的余弦计算。
+// lambda 大于等于 1 时只按相关度取前 k 条
+func Diversify(docs []Document, k int, lambda float64) []Document {
+	if len(docs) <= k || lambda >= 1 {
+		return docs[:min(k, len(docs))]
+	}
+
+	relevance := make([]float64, len(docs))
+	reranked := false
+	for _, doc := range docs {
+		reranked = reranked || doc.RerankScore > 0
+	}
+	var best float64
+	for i, doc := range docs {
+		relevance[i] = float64(doc.Score)
+		if reranked {
+			relevance[i] = float64(doc.RerankScore)
+		}
+		best = max(best, relevance[i])
+	}
+	if best > 0 {
+		for i := range relevance {
+			relevance[i] /= best
+		}
+	}
+
+	vectors := make([]map[string]float64, len(docs))
+	for i, doc := range docs {
+		vectors[i] = termVector(doc.Text)
+	}
+
+	selected := make([]int, 0, k)
+	used := make([]bool, len(docs))
+	maxSim := make([]float64, len(docs)) // 与已选结果的最大相似度
+	for len(selected) < k {
+		pick, pickScore := -1, math.Inf(-1)
+		for i := range docs {
+			if used[i] {
+				continue
+			}
+			if score := lambda*relevance[i] - (1-lambda)*maxSim[i]; score > pickScore {
+				pick, pickScore = i, score
+			}
+		}
+		used[pick] = true
+		selected = append(selected, pick)
+		for i := range docs {
+			if !used[i] {
+				maxSim[i] = max(maxSim[i], cosine(vectors[i], vectors[pick]))
+			}
+		}
+	}
+
+	result := make([]Document, len(selected))
+	for i, idx := range selected {
+		result[i] = docs[idx]
+	}
+	return result
+}
+
+// PackContext 按排名依次放入不超过 budget 个 token 的块，放不下的块截断或丢弃；
+// 再把同一段落中相邻或重叠的块合并为一条，合并后的位置取排名最高的块的位置
+func PackContext(docs []Document, budget int) []Document {
+	var packed []Document
+	used := 0
+	for _, doc := range docs {
+		tokens := EstimateTokens(doc.Text)
+		if used+tokens > budget {
+			remaining := budget - used
+			if remaining < minPackedTokens {
+				break
+			}
+			doc.Text = truncateTokens(doc.Text, remaining)
+			tokens = EstimateTokens(doc.Text)
+			// 截断后结束位置不再准确，合并时不按位置去重
+			metadata := make(map[string]interface{}, len(doc.Metadata))
+			for k, v := range doc.Metadata {
+				metadata[k] = v
+			}
+			delete(metadata, MetaEndOffset)
+			doc.Metadata = metadata
+		}
+		packed = append(packed, doc)
+		used += tokens
+	}
+	return mergeAdjacent(packed)
+}
+
+// mergeAdjacent 合并同一父文档中块序号相邻的块，重叠部分只保留一份
+func mergeAdjacent(docs []Document) []Document {
+	groups := map[string][]int{}
+	var keys []string
+	for i, doc := range docs {
+		parent, _ := doc.Metadata[MetaParentID].(string)
+		if parent == "" {
+			parent = "\x00" + doc.ID
+		}
+		if _, ok := groups[parent]; !ok {
+			keys = append(keys, parent)
+		}
+		groups[parent] = append(groups[parent], i)
+	}
+
+	type ranked struct {
+		rank int
+		doc  Document
+	}
+	var merged []ranked
+	for _, key := range keys {
+		idx := groups[key]
+		sort.Slice(idx, func(a, b int) bool {
+			return metaInt(docs[idx[a]].Metadata[MetaChunkIndex]) < metaInt(docs[idx[b]].Metadata[MetaChunkIndex])
+		})
+
+		current := ranked{rank: idx[0], doc: docs[idx[0]]}
+		for _, i := range idx[1:] {
+			next := docs[i]
+			if metaInt(next.Metadata[MetaChunkIndex]) != metaInt(current.doc.Metadata[MetaChunkIndex])+1 {
+				merged = append(merged, current)
+				current = ranked{rank: i, doc: next}
+				continue
+			}
+			current.doc = joinChunks(current.doc, next)
+			current.rank = min(current.rank, i)
+		}
+		merged = append(merged, current)
+	}
+
+	sort.Slice(merged, func(a, b int) bool { return merged[a].rank < merged[b].rank })
+	result := make([]Document, len(merged))
+	for i, m := range merged {
+		result[i] = m.doc
+	}
+	return result
+}
+
+// joinChunks 把 next 接在 prev 之后：按起止位置去掉重叠的文字，元数据沿用 prev，结束位置和块序号取 next 的
+func joinChunks(prev, next Document) Document {
+	text := next.Text
+	prevEnd := metaInt(prev.Metadata[MetaEndOffset])
+	nextStart := metaInt(next.Metadata[MetaStartOffset])
+	if overlap := int(prevEnd - nextStart); prevEnd > 0 && overlap > 0 {
+		runes := []rune(text)
+		text = string(runes[min(overlap, len(runes)):])
+	} else {
+		text = "\n" + text
+	}
+
+	metadata := make(map[string]interface{}, len(prev.Metadata))
+	for k, v := range prev.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaChunkIndex] = next.Metadata[MetaChunkIndex]
+	if end, ok := next.Metadata[MetaEndOffset]; ok {
+		metadata[MetaEndOffset] = end
+	} else {
+		delete(metadata, MetaEndOffset) // next 被截断过
+	}
+
+	prev.Text += text
+	prev.Metadata = metadata
+	prev.Score = max(prev.Score, next.Score)
+	prev.RerankScore = max(prev.RerankScore, next.RerankScore)
+	return prev
+}
+
+// truncateTokens 按估计的 token 数截断文本，末尾加省略号
+func truncateTokens(text string, tokens int) string {
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if EstimateTokens(string(runes[:mid])) <= tokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == len(runes) {
+		return text
+	}
+	return string(runes[:lo]) + "…"
+}
+
+func termVector(text string) map[string]float64 {
+	v := map[string]float64{}
+	for _, t := range Tokenize(text) {
+		v[t]++
+	}
+	return v
+}
+
+func cosine(a, b map[string]float64) float64 {
+	var dotProduct, na, nb float64
+	for t, x := range a {
+		na += x * x
+		dotProduct += x * b[t]
+	}
+	for _, y := range b {
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dotProduct / math.Sqrt(na*nb)
+}
+
+// ContextBudget 知识库内容可用的 token 数：从上下文长度中扣除预留给回答的长度和其余提示词（系统提示、历史、问题等），
+// 且不超过上下文长度的一半
+func ContextBudget(numCtx, reserve int, prompts ...string) int {
+	used := reserve
+	for _, p := range prompts {
+		used += EstimateTokens(p)
+	}
+	return max(min(numCtx-used, numCtx/2), 0)
+}
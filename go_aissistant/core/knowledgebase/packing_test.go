@@ -0,0 +1,251 @@
+package knowledgebase
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testChunk 父文档 parent 的第 index 块，位于原文 [start, end)
+func testChunk(parent string, index, start, end int, text string) Document {
+	return Document{
+		ID:   parent + "#" + string(rune('0'+index)),
+		Text: text,
+		Metadata: map[string]interface{}{
+			MetaParentID:    parent,
+			MetaChunkIndex:  index,
+			MetaStartOffset: start,
+			MetaEndOffset:   end,
+		},
+	}
+}
+
+func docTexts(docs []Document) []string {
+	texts := make([]string, len(docs))
+	for i, d := range docs {
+		texts[i] = d.Text
+	}
+	return texts
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"中文检索", 4},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"a b\tc\nd", 1},
+		{"用 Docker 部署", 5},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPackContext(t *testing.T) {
+	long := func(r string, n int) string { return strings.Repeat(r, n) }
+
+	tests := []struct {
+		name   string
+		docs   []Document
+		budget int
+		want   []string
+	}{
+		{
+			name:   "全部放得下时保持排名",
+			docs:   []Document{testChunk("b", 0, 0, 2, "乙乙"), testChunk("a", 0, 0, 2, "甲甲")},
+			budget: 100,
+			want:   []string{"乙乙", "甲甲"},
+		},
+		{
+			name:   "超出预算的块截断，之后的块丢弃",
+			docs:   []Document{testChunk("a", 0, 0, 100, long("甲", 100)), testChunk("b", 0, 0, 200, long("乙", 200)), testChunk("c", 0, 0, 1, "丙")},
+			budget: 200,
+			want:   []string{long("甲", 100), long("乙", 100) + "…"},
+		},
+		{
+			name:   "剩余不足 minPackedTokens 时不再放入",
+			docs:   []Document{testChunk("a", 0, 0, 100, long("甲", 100)), testChunk("b", 0, 0, 200, long("乙", 200)), testChunk("c", 0, 0, 1, "丙")},
+			budget: 100 + minPackedTokens - 1,
+			want:   []string{long("甲", 100)},
+		},
+		{
+			name: "相邻的块按位置去掉重叠后合并，位置取排名最高的块",
+			docs: []Document{
+				testChunk("a", 1, 7, 17, "hijklmnopq"),
+				testChunk("b", 0, 0, 2, "乙乙"),
+				testChunk("a", 0, 0, 10, "abcdefghij"),
+			},
+			budget: 100,
+			want:   []string{"abcdefghijklmnopq", "乙乙"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := docTexts(PackContext(tt.docs, tt.budget)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PackContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackContextTruncatedOffsets(t *testing.T) {
+	first := testChunk("a", 0, 0, 100, strings.Repeat("甲", 100))
+	second := testChunk("a", 1, 100, 200, strings.Repeat("乙", 100))
+	packed := PackContext([]Document{first, second}, 100+minPackedTokens)
+
+	if len(packed) != 1 {
+		t.Fatalf("相邻的块应合并为一条，得到 %d 条", len(packed))
+	}
+	if want := strings.Repeat("甲", 100) + "\n" + strings.Repeat("乙", minPackedTokens) + "…"; packed[0].Text != want {
+		t.Errorf("合并后文字 = %q", packed[0].Text)
+	}
+	if _, ok := packed[0].Metadata[MetaEndOffset]; ok {
+		t.Error("末尾截断后不应保留结束位置")
+	}
+	if second.Metadata[MetaEndOffset] != 200 {
+		t.Error("截断不应修改原文档的元数据")
+	}
+}
+
+func TestMergeAdjacent(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []Document
+		want []string
+	}{
+		{
+			name: "中文按字计算重叠",
+			docs: []Document{testChunk("a", 0, 0, 6, "一二三四五六"), testChunk("a", 1, 4, 10, "五六七八九十")},
+			want: []string{"一二三四五六七八九十"},
+		},
+		{
+			name: "三块连续合并",
+			docs: []Document{
+				testChunk("a", 2, 14, 24, "opqrstuvwx"),
+				testChunk("a", 0, 0, 10, "abcdefghij"),
+				testChunk("a", 1, 7, 17, "hijklmnopq"),
+			},
+			want: []string{"abcdefghijklmnopqrstuvwx"},
+		},
+		{
+			name: "没有重叠的相邻块换行连接",
+			docs: []Document{testChunk("a", 0, 0, 4, "abcd"), testChunk("a", 1, 4, 8, "efgh")},
+			want: []string{"abcd\nefgh"},
+		},
+		{
+			name: "块序号不相邻时不合并",
+			docs: []Document{testChunk("a", 2, 20, 30, "third"), testChunk("a", 0, 0, 10, "first")},
+			want: []string{"third", "first"},
+		},
+		{
+			name: "不同父文档不合并",
+			docs: []Document{testChunk("a", 0, 0, 10, "abcdefghij"), testChunk("b", 1, 7, 17, "hijklmnopq")},
+			want: []string{"abcdefghij", "hijklmnopq"},
+		},
+		{
+			name: "没有父文档的结果各自保留",
+			docs: []Document{{ID: "x", Text: "x"}, {ID: "y", Text: "y"}},
+			want: []string{"x", "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := docTexts(mergeAdjacent(tt.docs)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeAdjacent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinChunksMetadata(t *testing.T) {
+	prev := testChunk("a", 0, 0, 10, "abcdefghij")
+	prev.Metadata["source"] = "a.txt"
+	prev.Score = 0.5
+	next := testChunk("a", 1, 7, 17, "hijklmnopq")
+	next.Score = 0.8
+	next.RerankScore = 0.3
+
+	joined := joinChunks(prev, next)
+	want := map[string]interface{}{
+		MetaParentID:    "a",
+		MetaChunkIndex:  1,
+		MetaStartOffset: 0,
+		MetaEndOffset:   17,
+		"source":        "a.txt",
+	}
+	if !reflect.DeepEqual(joined.Metadata, want) {
+		t.Errorf("合并后元数据 = %v, want %v", joined.Metadata, want)
+	}
+	if joined.ID != prev.ID || joined.Score != 0.8 || joined.RerankScore != 0.3 {
+		t.Errorf("合并后 ID 和得分不正确: %+v", joined)
+	}
+	if prev.Metadata[MetaEndOffset] != 10 {
+		t.Error("合并不应修改原文档的元数据")
+	}
+}
+
+func TestDiversify(t *testing.T) {
+	docs := []Document{
+		{ID: "a", Text: "docker 安装步骤说明", Score: 1},
+		{ID: "a2", Text: "docker 安装步骤说明", Score: 0.95},
+		{ID: "b", Text: "修改配置文件路径", Score: 0.6},
+	}
+	ids := func(docs []Document) []string {
+		var ids []string
+		for _, d := range docs {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		docs   []Document
+		k      int
+		lambda float64
+		want   []string
+	}{
+		{"lambda 为 1 时按相关度取前 k 条", docs, 2, 1, []string{"a", "a2"}},
+		{"重复的块让位于不同的内容", docs, 2, 0.5, []string{"a", "b"}},
+		{"候选不超过 k 条时全部保留", docs, 5, 0.5, []string{"a", "a2", "b"}},
+		{"k 为 0", docs, 0, 0.5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(Diversify(tt.docs, tt.k, tt.lambda)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diversify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	reranked := append([]Document(nil), docs...)
+	reranked[2].RerankScore = 0.9
+	reranked[0].RerankScore = 0.2
+	reranked[1].RerankScore = 0.1
+	if got := ids(Diversify(reranked, 1, 0.7)); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("重排后应按重排得分选择，得到 %v", got)
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	tests := []struct {
+		numCtx, reserve int
+		prompts         []string
+		want            int
+	}{
+		{2048, 512, nil, 1024},
+		{2048, 512, []string{strings.Repeat("中", 1000)}, 536},
+		{2048, 512, []string{strings.Repeat("中", 2000)}, 0},
+	}
+	for _, tt := range tests {
+		if got := ContextBudget(tt.numCtx, tt.reserve, tt.prompts...); got != tt.want {
+			t.Errorf("ContextBudget(%d, %d, %d 个提示) = %d, want %d", tt.numCtx, tt.reserve, len(tt.prompts), got, tt.want)
+		}
+	}
+}
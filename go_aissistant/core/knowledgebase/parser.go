@@ -6,73 +6,159 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/fighthorse/aicode/go_aissistant/config"
 )
 
+// MetaSection 文档片段的标题，如 DOCX 的“页眉”“脚注”
+const MetaSection = "section"
+
 type FileParser struct {
 	SupportedFormats map[string]bool
+	// IncludeExtras 是否导入页眉页脚、脚注、批注等附属内容，默认不导入
+	IncludeExtras bool
+	// RowTemplate 表格、JSON 每行记录的文本模板（text/template），为空时逐列输出 "列名: 值"
+	RowTemplate string
+}
+
+// Section 文件中独立的一段内容，如 DOCX 的正文和页眉页脚。
+// Metadata 为该片段特有的元数据，导入时合并到文档元数据中。
+type Section struct {
+	Title    string
+	Text     string
+	Metadata map[string]interface{}
 }
 
 func NewFileParser() *FileParser {
-	return &FileParser{
+	p := &FileParser{
 		SupportedFormats: map[string]bool{
-			".txt":  true,
-			".pdf":  true,
-			".docx": true,
+			".txt":      true,
+			".pdf":      true,
+			".docx":     true,
+			".odt":      true,
+			".rtf":      true,
+			".epub":     true,
+			".pptx":     true,
+			".md":       true,
+			".markdown": true,
+			".html":     true,
+			".htm":      true,
+			".csv":      true,
+			".tsv":      true,
+			".xlsx":     true,
+			".json":     true,
+			".jsonl":    true,
 		},
 	}
+	for ext := range codeLanguages {
+		p.SupportedFormats[ext] = true
+	}
+	return p
 }
 
+// NewFileParserFromConfig 根据配置创建解析器
+func NewFileParserFromConfig(conf *config.AppConfig) *FileParser {
+	p := NewFileParser()
+	p.RowTemplate = conf.RowTemplate
+	p.IncludeExtras = conf.ParseExtras
+	return p
+}
+
+// ParseFile 返回文件的全部文本，各片段之间以空行分隔
 func (p *FileParser) ParseFile(path string) (string, error) {
+	sections, err := p.ParseSections(path)
+	if err != nil {
+		return "", err
+	}
+
+	texts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		if text := strings.TrimSpace(s.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ParseSections 按文件格式解析为若干片段
+func (p *FileParser) ParseSections(path string) ([]Section, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 
 	switch ext {
 	case ".txt":
-		return p.parseTextFile(path)
+		return singleSection(p.parseTextFile(path))
 	case ".pdf":
-		return p.parsePDFFile(path)
+		return parsePDFSections(path)
 	case ".docx":
-		return p.parseDocxFile(path)
-	default:
-		return "", fmt.Errorf("不支持的文件格式: %s", ext)
+		return parseDocxSections(path, p.IncludeExtras)
+	case ".odt":
+		return parseODTSections(path, p.IncludeExtras)
+	case ".rtf":
+		return singleSection(parseRTFFile(path))
+	case ".epub":
+		return parseEPUBSections(path)
+	case ".pptx":
+		return parsePPTXSections(path)
+	case ".md", ".markdown":
+		return parseMarkdownSections(path)
+	case ".html", ".htm":
+		return parseHTMLSections(path)
+	case ".csv", ".tsv", ".xlsx", ".json", ".jsonl":
+		r, err := newRowRenderer(p.RowTemplate)
+		if err != nil {
+			return nil, err
+		}
+		switch ext {
+		case ".xlsx":
+			return parseXLSXSections(path, r)
+		case ".json", ".jsonl":
+			return parseJSONSections(path, r)
+		}
+		return parseCSVSections(path, r)
 	}
-}
 
-func (p *FileParser) parseTextFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+	if _, ok := codeLanguages[ext]; ok {
+		return parseCodeSections(path)
 	}
-	return string(content), nil
+	return nil, fmt.Errorf("不支持的文件格式: %s", ext)
 }
 
-func (p *FileParser) parsePDFFile(path string) (string, error) {
-	tmpDir, err := os.MkdirTemp("", "pdfextract")
+func singleSection(text string, err error) ([]Section, error) {
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer os.RemoveAll(tmpDir)
+	return []Section{{Text: text}}, nil
+}
 
-	if err := api.ExtractContentFile(path, tmpDir, nil, nil); err != nil {
-		return "", err
-	}
+// SectionDocuments 每个非空片段生成一篇待切分的文档，ID 为 baseID 加片段序号，
+// 元数据包含公共元数据、片段标题和片段自身的元数据
+func SectionDocuments(baseID string, sections []Section, metadata map[string]interface{}) []Document {
+	var docs []Document
+	for i, s := range sections {
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
 
-	var builder strings.Builder
-	filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && strings.HasSuffix(path, ".txt") {
-			content, _ := os.ReadFile(path)
-			builder.Write(content)
-			builder.WriteString("\n\n")
+		meta := make(map[string]interface{}, len(metadata)+len(s.Metadata)+1)
+		for k, v := range metadata {
+			meta[k] = v
+		}
+		if s.Title != "" {
+			meta[MetaSection] = s.Title
+		}
+		for k, v := range s.Metadata {
+			meta[k] = v
 		}
-		return nil
-	})
 
-	return builder.String(), nil
+		id := baseID
+		if len(sections) > 1 {
+			id = fmt.Sprintf("%s-%d", baseID, i)
+		}
+		docs = append(docs, Document{ID: id, Text: s.Text, Metadata: meta})
+	}
+	return docs
 }
 
-func (p *FileParser) parseDocxFile(path string) (string, error) {
-	// 使用gooxml等库实现DOCX解析
-	// 此处为示例占位实现
+func (p *FileParser) parseTextFile(path string) (string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
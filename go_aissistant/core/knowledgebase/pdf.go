@@ -0,0 +1,380 @@
+package knowledgebase
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// MetaPage PDF 页码，从 1 开始
+const MetaPage = "page"
+
+// 表单 XObject 可以嵌套引用，限制深度避免循环引用
+const pdfMaxFormDepth = 8
+
+var (
+	ErrPDFEncrypted = errors.New("PDF 文件已加密，需要密码才能读取")
+	ErrPDFImageOnly = errors.New("PDF 中没有可提取的文字，可能是扫描件或纯图片，需要先进行 OCR")
+)
+
+// parsePDFSections 逐页提取 PDF 文字，每页为一个片段并在元数据中记录页码。
+// 文字由内容流中的文本操作符（Tj、TJ、'、"）按字体的 ToUnicode 映射或编码解码，
+// 根据文本位置的变化判断换行和分段
+func parsePDFSections(path string) ([]Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.EXTRACTCONTENT
+	conf.ValidationMode = model.ValidationRelaxed
+	ctx, err := api.ReadContext(f, conf)
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return nil, fmt.Errorf("%w: %s", ErrPDFEncrypted, filepath.Base(path))
+		}
+		return nil, fmt.Errorf("读取PDF失败: %v", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("读取PDF页数失败: %v", err)
+	}
+
+	x := &pdfTextExtractor{xref: ctx.XRefTable, fonts: map[int]*pdfFont{}}
+	var sections []Section
+	for page := 1; page <= ctx.PageCount; page++ {
+		d, _, inherited, err := ctx.PageDict(page, false)
+		if err != nil {
+			return nil, fmt.Errorf("读取PDF第 %d 页失败: %v", page, err)
+		}
+		content, err := ctx.PageContent(d)
+		if err != nil && err != model.ErrNoContent {
+			return nil, fmt.Errorf("读取PDF第 %d 页内容失败: %v", page, err)
+		}
+
+		text := x.pageText(content, inherited.Resources)
+		if text == "" {
+			continue
+		}
+		sections = append(sections, Section{
+			Title:    fmt.Sprintf("第 %d 页", page),
+			Text:     text,
+			Metadata: map[string]interface{}{MetaPage: page},
+		})
+	}
+
+	if len(sections) == 0 {
+		if x.images > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPDFImageOnly, filepath.Base(path))
+		}
+		return nil, fmt.Errorf("PDF 中没有可提取的文字: %s", filepath.Base(path))
+	}
+	return sections, nil
+}
+
+// pdfTextExtractor 在同一文件的各页之间共享字体缓存
+type pdfTextExtractor struct {
+	xref   *model.XRefTable
+	fonts  map[int]*pdfFont // 字体对象号 -> 字体
+	images int              // 遇到的图片数，用于识别扫描件
+}
+
+// pdfTextState 一页内容的文本状态。y 坐标只用来判断换行，不考虑旋转和倾斜
+type pdfTextState struct {
+	out      strings.Builder
+	font     *pdfFont
+	fontSize float64
+	leading  float64
+
+	lineY  float64 // 文本行矩阵的 y 坐标
+	scaleY float64 // 文本矩阵的纵向缩放
+
+	ctmY, ctmScale float64 // 图形状态中变换矩阵的纵向平移和缩放
+	saved          [][2]float64
+
+	lastY   float64 // 上一段文字的页面 y 坐标
+	started bool
+	space   bool // 下一段文字与上一段之间有间隔
+}
+
+func (x *pdfTextExtractor) pageText(content []byte, resources types.Dict) string {
+	st := &pdfTextState{scaleY: 1, ctmScale: 1}
+	x.run(content, resources, st, 0)
+
+	lines := strings.Split(st.out.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// run 执行内容流中与文字位置和输出有关的操作符
+func (x *pdfTextExtractor) run(content []byte, resources types.Dict, st *pdfTextState, depth int) {
+	lex := &pdfLexer{data: content}
+	var operands []interface{}
+	num := func(i int) float64 {
+		if i < len(operands) {
+			if v, ok := operands[i].(float64); ok {
+				return v
+			}
+		}
+		return 0
+	}
+	str := func(i int) []byte {
+		if i < len(operands) {
+			if v, ok := operands[i].(pdfString); ok {
+				return v
+			}
+		}
+		return nil
+	}
+
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfOp)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch op {
+		case "q":
+			st.saved = append(st.saved, [2]float64{st.ctmY, st.ctmScale})
+		case "Q":
+			if n := len(st.saved); n > 0 {
+				st.ctmY, st.ctmScale = st.saved[n-1][0], st.saved[n-1][1]
+				st.saved = st.saved[:n-1]
+			}
+		case "cm":
+			if len(operands) >= 6 {
+				st.ctmY += num(5) * st.ctmScale
+				st.ctmScale *= pdfScale(num(3), num(1))
+			}
+		case "BT":
+			st.lineY, st.scaleY = 0, 1
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					st.font = x.font(resources, string(name))
+				}
+				st.fontSize = num(1)
+			}
+		case "TL":
+			st.leading = num(0)
+		case "Td":
+			st.move(num(0), num(1))
+		case "TD":
+			st.leading = -num(1)
+			st.move(num(0), num(1))
+		case "Tm":
+			if len(operands) >= 6 {
+				y := num(5)
+				if y == st.lineY {
+					st.space = true
+				}
+				st.lineY = y
+				st.scaleY = pdfScale(num(3), num(1))
+			}
+		case "T*":
+			st.move(0, -st.leading)
+		case "Tj":
+			st.show(str(0))
+		case "'":
+			st.move(0, -st.leading)
+			st.show(str(0))
+		case "\"":
+			st.move(0, -st.leading)
+			st.show(str(2))
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[0].([]interface{})
+				for _, item := range items {
+					switch v := item.(type) {
+					case pdfString:
+						st.show(v)
+					case float64:
+						// 以千分之一字号为单位的水平偏移，较大的负值表示单词间隔
+						if v < -200 {
+							st.space = true
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					x.xObject(resources, string(name), st, depth)
+				}
+			}
+		case "BI":
+			x.images++
+		}
+		operands = operands[:0]
+	}
+}
+
+// xObject 图片只计数，表单 XObject 按自己的资源递归提取
+func (x *pdfTextExtractor) xObject(resources types.Dict, name string, st *pdfTextState, depth int) {
+	xobjects := x.dict(resources, "XObject")
+	if xobjects == nil {
+		return
+	}
+	obj, ok := xobjects.Find(name)
+	if !ok {
+		return
+	}
+	sd, _, err := x.xref.DereferenceStreamDict(obj)
+	if err != nil || sd == nil {
+		return
+	}
+
+	switch subtype := sd.Subtype(); {
+	case subtype != nil && *subtype == "Image":
+		x.images++
+	case subtype != nil && *subtype == "Form" && depth < pdfMaxFormDepth:
+		if err := sd.Decode(); err != nil {
+			return
+		}
+		formResources := x.dict(sd.Dict, "Resources")
+		if formResources == nil {
+			formResources = resources
+		}
+		saved := len(st.saved)
+		st.saved = append(st.saved, [2]float64{st.ctmY, st.ctmScale})
+		x.run(sd.Content, formResources, st, depth+1)
+		st.ctmY, st.ctmScale = st.saved[saved][0], st.saved[saved][1]
+		st.saved = st.saved[:saved]
+	}
+}
+
+func (x *pdfTextExtractor) dict(d types.Dict, key string) types.Dict {
+	if d == nil {
+		return nil
+	}
+	obj, ok := d.Find(key)
+	if !ok {
+		return nil
+	}
+	sub, err := x.xref.DereferenceDict(obj)
+	if err != nil {
+		return nil
+	}
+	return sub
+}
+
+// font 按资源名查找字体，间接引用的字体按对象号缓存
+func (x *pdfTextExtractor) font(resources types.Dict, name string) *pdfFont {
+	fonts := x.dict(resources, "Font")
+	if fonts == nil {
+		return nil
+	}
+	obj, ok := fonts.Find(name)
+	if !ok {
+		return nil
+	}
+
+	key := -1
+	if ref, ok := obj.(types.IndirectRef); ok {
+		key = ref.ObjectNumber.Value()
+		if f, ok := x.fonts[key]; ok {
+			return f
+		}
+	}
+	d, err := x.xref.DereferenceDict(obj)
+	if err != nil || d == nil {
+		return nil
+	}
+
+	f := x.loadFont(d)
+	if key >= 0 {
+		x.fonts[key] = f
+	}
+	return f
+}
+
+func (x *pdfTextExtractor) loadFont(d types.Dict) *pdfFont {
+	f := &pdfFont{codeLen: 1}
+	if subtype := d.Subtype(); subtype != nil && *subtype == "Type0" {
+		f.composite = true
+		f.codeLen = 2
+		if enc := d.NameEntry("Encoding"); enc != nil {
+			f.setPredefinedCMap(*enc)
+		}
+	} else {
+		f.setSimpleEncoding(x.xref, d)
+	}
+
+	if obj, ok := d.Find("ToUnicode"); ok {
+		if sd, _, err := x.xref.DereferenceStreamDict(obj); err == nil && sd != nil && sd.Decode() == nil {
+			f.parseToUnicode(sd.Content)
+		}
+	}
+	return f
+}
+
+// move 文本行移动 (tx, ty)，同一行内的移动视为单词间隔
+func (st *pdfTextState) move(tx, ty float64) {
+	if ty == 0 {
+		if tx != 0 {
+			st.space = true
+		}
+		return
+	}
+	st.lineY += ty * st.scaleY
+}
+
+// show 输出一段文字：与上一段不在同一行时换行，间距明显大于行高时空一行
+func (st *pdfTextState) show(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	text := st.font.decode(b)
+	if text == "" {
+		return
+	}
+
+	y := st.ctmY + st.lineY*st.ctmScale
+	if st.started {
+		lineHeight := math.Abs(st.fontSize * st.scaleY * st.ctmScale)
+		if lineHeight == 0 {
+			lineHeight = 1
+		}
+		switch dy := math.Abs(y - st.lastY); {
+		case dy > lineHeight*1.8:
+			st.out.WriteString("\n\n")
+		case dy > lineHeight*0.5:
+			st.out.WriteString("\n")
+		case st.space:
+			if s := st.out.String(); s != "" && pdfNeedsSpace(lastRune(s), firstRune(text)) {
+				st.out.WriteString(" ")
+			}
+		}
+	}
+	st.out.WriteString(text)
+	st.lastY = y
+	st.started = true
+	st.space = false
+}
+
+// pdfScale 矩阵的纵向缩放，旋转 90 度的矩阵 d 为 0，取 b
+func pdfScale(d, b float64) float64 {
+	if d != 0 {
+		return math.Abs(d)
+	}
+	if b != 0 {
+		return math.Abs(b)
+	}
+	return 1
+}
@@ -0,0 +1,96 @@
+package knowledgebase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// writeTestPDF 按顺序写入对象（第一个为 Catalog）并生成交叉引用表
+func writeTestPDF(t *testing.T, name string, objects ...string) string {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func pdfStream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func writeTextPDF(t *testing.T) string {
+	page := "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 7 0 R >> >> /Contents %d 0 R >>"
+	return writeTestPDF(t, "text.pdf",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+		fmt.Sprintf(page, 4),
+		pdfStream("", "BT /F1 12 Tf 72 720 Td (Hello page one) Tj ET"),
+		fmt.Sprintf(page, 6),
+		pdfStream("", "BT /F1 12 Tf 14 TL 72 720 Td (Second) Tj [( p) -20 (age)] TJ T* (next line) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+}
+
+func TestParsePDFSections(t *testing.T) {
+	sections, err := parsePDFSections(writeTextPDF(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("得到 %d 页，应为 2", len(sections))
+	}
+	want := []string{"Hello page one", "Second page\nnext line"}
+	for i, s := range sections {
+		if s.Text != want[i] {
+			t.Errorf("第 %d 页文字 = %q, want %q", i+1, s.Text, want[i])
+		}
+		if s.Metadata[MetaPage] != i+1 {
+			t.Errorf("第 %d 页的页码元数据为 %v", i+1, s.Metadata[MetaPage])
+		}
+	}
+}
+
+func TestParsePDFImageOnly(t *testing.T) {
+	path := writeTestPDF(t, "scan.pdf",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /XObject << /Im1 5 0 R >> >> /Contents 4 0 R >>",
+		pdfStream("", "q 612 0 0 792 0 0 cm /Im1 Do Q"),
+		pdfStream("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", "\x80"),
+	)
+	if _, err := parsePDFSections(path); !errors.Is(err, ErrPDFImageOnly) {
+		t.Errorf("纯图片 PDF 应返回 ErrPDFImageOnly，得到 %v", err)
+	}
+}
+
+func TestParsePDFEncrypted(t *testing.T) {
+	encrypted := filepath.Join(t.TempDir(), "encrypted.pdf")
+	if err := api.EncryptFile(writeTextPDF(t), encrypted, model.NewAESConfiguration("secret", "owner", 256)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsePDFSections(encrypted); !errors.Is(err, ErrPDFEncrypted) {
+		t.Errorf("需要打开密码的 PDF 应返回 ErrPDFEncrypted，得到 %v", err)
+	}
+}
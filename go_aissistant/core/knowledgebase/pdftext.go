@@ -0,0 +1,545 @@
+package knowledgebase
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ToUnicode 中单个 bfrange 最多展开的编码数，防止异常文件占用过多内存
+const pdfMaxCMapRange = 1 << 16
+
+// 内容流和 CMap 的词法单元
+type (
+	pdfString []byte
+	pdfName   string
+	pdfOp     string
+	pdfDict   struct{}
+)
+
+// pdfLexer 解析内容流和 CMap 使用的 PDF 语法：数字、字符串、名称、数组、字典和操作符
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// next 返回下一个词法单元，到达末尾时 ok 为 false
+func (l *pdfLexer) next() (interface{}, bool) {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return nil, false
+		}
+
+		c := l.data[l.pos]
+		switch {
+		case c == '(':
+			return l.literalString(), true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			for {
+				l.skipSpace()
+				if l.pos >= len(l.data) {
+					return pdfDict{}, true
+				}
+				if bytes.HasPrefix(l.data[l.pos:], []byte(">>")) {
+					l.pos += 2
+					return pdfDict{}, true
+				}
+				if _, ok := l.next(); !ok {
+					return pdfDict{}, true
+				}
+			}
+		case c == '<':
+			return l.hexString(), true
+		case c == '[':
+			l.pos++
+			var items []interface{}
+			for {
+				l.skipSpace()
+				if l.pos >= len(l.data) {
+					return items, true
+				}
+				if l.data[l.pos] == ']' {
+					l.pos++
+					return items, true
+				}
+				item, ok := l.next()
+				if !ok {
+					return items, true
+				}
+				items = append(items, item)
+			}
+		case c == '/':
+			l.pos++
+			return pdfName(l.regular()), true
+		case c == ')' || c == '>' || c == ']' || c == '{' || c == '}':
+			// 多余的分隔符
+			l.pos++
+		default:
+			word := l.regular()
+			if word == "" {
+				l.pos++
+				continue
+			}
+			if c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9' {
+				if v, err := strconv.ParseFloat(word, 64); err == nil {
+					return v, true
+				}
+			}
+			if word == "BI" {
+				l.skipInlineImage()
+			}
+			return pdfOp(word), true
+		}
+	}
+}
+
+func (l *pdfLexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return b
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// 行尾的反斜杠表示续行
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		b[i] = byte(v)
+	}
+	return b
+}
+
+// skipInlineImage 跳过内联图片 BI ... ID <二进制数据> EI
+func (l *pdfLexer) skipInlineImage() {
+	idx := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if idx < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += idx + 3
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		end := l.pos + i
+		before := end == 0 || isPDFSpace(l.data[end-1])
+		after := end+2 >= len(l.data) || isPDFSpace(l.data[end+2])
+		l.pos = end + 2
+		if before && after {
+			return
+		}
+	}
+}
+
+type pdfCodeRange struct {
+	lo, hi []byte
+}
+
+// pdfFont 把字符串中的字符编码转为文字：优先使用 ToUnicode 映射，
+// 其次使用预定义 CMap（如 UniGB-UCS2-H、GBK-EUC-H）或简单字体的编码表
+type pdfFont struct {
+	composite bool
+	codeLen   int
+	codespace []pdfCodeRange
+	toUnicode map[uint32]string
+
+	utf16   bool              // 编码即 UTF-16BE
+	charset encoding.Encoding // 编码为多字节字符集
+	table   *[256]string      // 简单字体的单字节编码表
+}
+
+// setPredefinedCMap 组合字体的预定义 CMap，Identity-H/V 只能依赖 ToUnicode
+func (f *pdfFont) setPredefinedCMap(name string) {
+	switch {
+	case strings.HasPrefix(name, "Uni") && (strings.Contains(name, "UCS2") || strings.Contains(name, "UTF16")):
+		f.utf16 = true
+	case strings.HasPrefix(name, "GB"):
+		f.charset = simplifiedchinese.GBK
+	case strings.HasPrefix(name, "B5") || strings.HasPrefix(name, "ETen") || strings.HasPrefix(name, "HKscs"):
+		f.charset = traditionalchinese.Big5
+	case strings.Contains(name, "RKSJ"):
+		f.charset = japanese.ShiftJIS
+	case strings.HasPrefix(name, "KSC"):
+		f.charset = korean.EUCKR
+	}
+}
+
+// setSimpleEncoding 简单字体的编码：基础编码（WinAnsi 或 MacRoman）加上 Differences 中按字形名改写的编码
+func (f *pdfFont) setSimpleEncoding(xref *model.XRefTable, d types.Dict) {
+	base := encoding.Encoding(charmap.Windows1252)
+	var differences types.Array
+
+	if obj, ok := d.Find("Encoding"); ok {
+		obj, _ = xref.Dereference(obj)
+		switch enc := obj.(type) {
+		case types.Name:
+			if enc == "MacRomanEncoding" {
+				base = charmap.Macintosh
+			}
+		case types.Dict:
+			if name := enc.NameEntry("BaseEncoding"); name != nil && *name == "MacRomanEncoding" {
+				base = charmap.Macintosh
+			}
+			differences, _ = xref.DereferenceArray(enc["Differences"])
+		}
+	}
+
+	var table [256]string
+	dec := base.NewDecoder()
+	for i := range table {
+		if s, err := dec.Bytes([]byte{byte(i)}); err == nil && i >= 32 {
+			table[i] = string(s)
+		}
+	}
+
+	code := 0
+	for _, item := range differences {
+		switch v := item.(type) {
+		case types.Integer:
+			code = v.Value()
+		case types.Name:
+			if code >= 0 && code < 256 {
+				if s := pdfGlyphText(string(v)); s != "" {
+					table[code] = s
+				}
+			}
+			code++
+		}
+	}
+	f.table = &table
+}
+
+// parseToUnicode 解析 ToUnicode CMap 中的 codespacerange、bfchar 和 bfrange
+func (f *pdfFont) parseToUnicode(data []byte) {
+	f.toUnicode = map[uint32]string{}
+	lex := &pdfLexer{data: data}
+	var operands []interface{}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfOp)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					f.codespace = append(f.codespace, pdfCodeRange{lo: lo, hi: hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].(pdfString)
+				if !ok {
+					continue
+				}
+				if s := pdfCMapTarget(operands[i+1], 0); s != "" {
+					f.toUnicode[pdfCode(src)] = s
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := pdfCode(lo), pdfCode(hi)
+				if end < start || end-start > pdfMaxCMapRange {
+					continue
+				}
+				for code := start; code <= end; code++ {
+					var s string
+					if list, ok := operands[i+2].([]interface{}); ok {
+						if int(code-start) < len(list) {
+							s = pdfCMapTarget(list[code-start], 0)
+						}
+					} else {
+						s = pdfCMapTarget(operands[i+2], code-start)
+					}
+					if s != "" {
+						f.toUnicode[code] = s
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// pdfCMapTarget bfchar、bfrange 的目标：UTF-16BE 字符串（bfrange 中最后一个码元加上偏移）或字形名
+func pdfCMapTarget(obj interface{}, offset uint32) string {
+	switch v := obj.(type) {
+	case pdfString:
+		if len(v) < 2 {
+			return ""
+		}
+		units := make([]uint16, len(v)/2)
+		for i := range units {
+			units[i] = uint16(v[2*i])<<8 | uint16(v[2*i+1])
+		}
+		units[len(units)-1] += uint16(offset)
+		return string(utf16.Decode(units))
+	case pdfName:
+		return pdfGlyphText(string(v))
+	}
+	return ""
+}
+
+func pdfCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// codeLength 按 codespacerange 确定字符串开头的字符编码长度
+func (f *pdfFont) codeLength(b []byte) int {
+	for _, r := range f.codespace {
+		if len(r.lo) > len(b) {
+			continue
+		}
+		match := true
+		for i := range r.lo {
+			if b[i] < r.lo[i] || b[i] > r.hi[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return len(r.lo)
+		}
+	}
+	return min(f.codeLen, len(b))
+}
+
+func (f *pdfFont) decode(b []byte) string {
+	if f == nil {
+		// 没有字体信息时按 WinAnsi 解码
+		s, _ := charmap.Windows1252.NewDecoder().Bytes(b)
+		return string(s)
+	}
+
+	if f.toUnicode != nil {
+		var out strings.Builder
+		for i := 0; i < len(b); {
+			n := f.codeLength(b[i:])
+			code := b[i : i+n]
+			i += n
+			if s, ok := f.toUnicode[pdfCode(code)]; ok {
+				out.WriteString(s)
+			} else if f.table != nil && n == 1 {
+				out.WriteString(f.table[code[0]])
+			}
+		}
+		return out.String()
+	}
+
+	switch {
+	case f.utf16:
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return string(utf16.Decode(units))
+	case f.charset != nil:
+		s, err := f.charset.NewDecoder().Bytes(b)
+		if err != nil {
+			return ""
+		}
+		return string(s)
+	case f.table != nil:
+		var out strings.Builder
+		for _, c := range b {
+			out.WriteString(f.table[c])
+		}
+		return out.String()
+	}
+	// Identity 编码的组合字体没有 ToUnicode 时无法还原文字
+	return ""
+}
+
+// 常用的非字母字形名
+var pdfGlyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(", "parenright": ")",
+	"asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+	"seven": "7", "eight": "8", "nine": "9", "colon": ":", "semicolon": ";", "less": "<",
+	"equal": "=", "greater": ">", "question": "?", "at": "@", "bracketleft": "[",
+	"backslash": "\\", "bracketright": "]", "asciicircum": "^", "underscore": "_",
+	"grave": "`", "braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"bullet": "•", "endash": "–", "emdash": "—", "quoteleft": "‘", "quoteright": "’",
+	"quotedblleft": "“", "quotedblright": "”", "ellipsis": "…", "fi": "fi", "fl": "fl",
+	"ff": "ff", "ffi": "ffi", "ffl": "ffl", "minus": "−", "degree": "°", "copyright": "©",
+	"registered": "®", "trademark": "™", "section": "§", "paragraph": "¶", "nbspace": " ",
+}
+
+// pdfGlyphText 字形名对应的文字，支持单个字符、uniXXXX、uXXXX 形式和常用符号名
+func pdfGlyphText(name string) string {
+	name, _, _ = strings.Cut(name, ".") // 去掉 .sc、.alt 等后缀
+	if utf8.RuneCountInString(name) == 1 {
+		return name
+	}
+	if s, ok := pdfGlyphNames[name]; ok {
+		return s
+	}
+	if hex, ok := strings.CutPrefix(name, "uni"); ok && len(hex)%4 == 0 && hex != "" {
+		var units []uint16
+		for i := 0; i < len(hex); i += 4 {
+			v, err := strconv.ParseUint(hex[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, uint16(v))
+		}
+		return string(utf16.Decode(units))
+	}
+	if hex, ok := strings.CutPrefix(name, "u"); ok && len(hex) >= 4 && len(hex) <= 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// pdfNeedsSpace 间隔两侧都不是中日韩文字时才补空格
+func pdfNeedsSpace(prev, next rune) bool {
+	if unicode.IsSpace(prev) || unicode.IsSpace(next) {
+		return false
+	}
+	return !isCJK(prev) && !isCJK(next)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= 0x3000 && r <= 0x303F || r >= 0xFF00 && r <= 0xFFEF
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
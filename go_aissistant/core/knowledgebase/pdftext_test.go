@@ -0,0 +1,170 @@
+package knowledgebase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func lexAll(data string) []interface{} {
+	lex := &pdfLexer{data: []byte(data)}
+	var tokens []interface{}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func TestPDFLexer(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []interface{}
+	}{
+		{
+			name: "数字、名称和操作符",
+			data: "/F1 12 Tf 72 -3.5 Td .5 TL",
+			want: []interface{}{pdfName("F1"), 12.0, pdfOp("Tf"), 72.0, -3.5, pdfOp("Td"), 0.5, pdfOp("TL")},
+		},
+		{
+			name: "字面字符串中的转义和嵌套括号",
+			data: `(Hello \(World\)\n) Tj (a(b)c) Tj`,
+			want: []interface{}{pdfString("Hello (World)\n"), pdfOp("Tj"), pdfString("a(b)c"), pdfOp("Tj")},
+		},
+		{
+			name: "八进制转义最多三位",
+			data: `(\101\60\0617)`,
+			want: []interface{}{pdfString("A017")},
+		},
+		{
+			name: "反斜杠续行",
+			data: "(ab\\\ncd\\\r\nef)",
+			want: []interface{}{pdfString("abcdef")},
+		},
+		{
+			name: "十六进制字符串忽略空白，奇数位补 0",
+			data: "<48 65 6C6c6F> <414>",
+			want: []interface{}{pdfString("Hello"), pdfString("A@")},
+		},
+		{
+			name: "数组和字典",
+			data: "[(a) -120 (b)] TJ << /Type /Font /W [1 2] >> BDC",
+			want: []interface{}{
+				[]interface{}{pdfString("a"), -120.0, pdfString("b")}, pdfOp("TJ"),
+				pdfDict{}, pdfOp("BDC"),
+			},
+		},
+		{
+			name: "注释",
+			data: "% 注释 (不是字符串)\nET",
+			want: []interface{}{pdfOp("ET")},
+		},
+		{
+			name: "跳过内联图片数据，数据中不在空白之间的 EI 不是结束标记",
+			data: "q BI /W 4 /H 1 /BPC 8 /CS /G ID xEIy(\x00\xff EI Q",
+			want: []interface{}{pdfOp("q"), pdfOp("BI"), pdfOp("Q")},
+		},
+		{
+			name: "没有结束的字符串和数组",
+			data: "[(abc",
+			want: []interface{}{[]interface{}{pdfString("abc")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lexAll(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+const testToUnicode = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Adobe-Identity-UCS def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+3 beginbfchar
+<0003> <0020>
+<0011> <4E2D>
+<0005> <00660069>
+endbfchar
+2 beginbfrange
+<0024> <0026> <0041>
+<0030> <0031> [<6587> <D83DDE00>]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+
+func TestParseToUnicode(t *testing.T) {
+	f := &pdfFont{composite: true, codeLen: 2}
+	f.parseToUnicode([]byte(testToUnicode))
+
+	want := map[uint32]string{
+		0x03: " ",
+		0x11: "中",
+		0x05: "fi",
+		0x24: "A",
+		0x25: "B",
+		0x26: "C",
+		0x30: "文",
+		0x31: "😀",
+	}
+	if !reflect.DeepEqual(f.toUnicode, want) {
+		t.Errorf("toUnicode = %q, want %q", f.toUnicode, want)
+	}
+	if got := f.decode([]byte{0x00, 0x11, 0x00, 0x30, 0x00, 0x03, 0x00, 0x24, 0x00, 0x05}); got != "中文 Afi" {
+		t.Errorf("decode = %q", got)
+	}
+}
+
+func TestParseToUnicodeMixedCodespace(t *testing.T) {
+	f := &pdfFont{composite: true, codeLen: 2}
+	f.parseToUnicode([]byte(`2 begincodespacerange
+<00> <80>
+<8140> <FEFE>
+endcodespacerange
+2 beginbfchar
+<41> <0041>
+<B0A1> <554A>
+endbfchar`))
+
+	if got := f.decode([]byte{0x41, 0xB0, 0xA1, 0x41}); got != "A啊A" {
+		t.Errorf("decode = %q, want %q", got, "A啊A")
+	}
+}
+
+func TestParseToUnicodeRejectsHugeRange(t *testing.T) {
+	f := &pdfFont{}
+	f.parseToUnicode([]byte("1 beginbfrange\n<00000000> <7FFFFFFF> <0041>\nendbfrange"))
+	if len(f.toUnicode) != 0 {
+		t.Errorf("超出上限的 bfrange 不应展开，得到 %d 项", len(f.toUnicode))
+	}
+}
+
+func TestPDFFontPredefinedCMap(t *testing.T) {
+	tests := []struct {
+		cmap string
+		code []byte
+		want string
+	}{
+		{"UniGB-UCS2-H", []byte{0x4E, 0x2D, 0x65, 0x87}, "中文"},
+		{"GBK-EUC-H", []byte{0xD6, 0xD0, 0xCE, 0xC4}, "中文"},
+		{"ETen-B5-H", []byte{0xA4, 0xA4, 0xA4, 0xE5}, "中文"},
+		{"Identity-H", []byte{0x00, 0x11}, ""},
+	}
+	for _, tt := range tests {
+		f := &pdfFont{composite: true, codeLen: 2}
+		f.setPredefinedCMap(tt.cmap)
+		if got := f.decode(tt.code); got != tt.want {
+			t.Errorf("%s: decode = %q, want %q", tt.cmap, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package knowledgebase
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MetaSlide 幻灯片页码，从 1 开始
+const MetaSlide = "slide"
+
+// parsePPTXSections 解析 PresentationML：每页幻灯片为一个片段，依次包含标题、各文本框和表格的文字，
+// 演讲者备注附在最后
+func parsePPTXSections(filePath string) ([]Section, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开PPTX文件失败: %v", err)
+	}
+	defer r.Close()
+
+	parts := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+
+	slides, err := pptxSlides(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for i, slidePath := range slides {
+		f, ok := parts[slidePath]
+		if !ok {
+			continue
+		}
+		slide, err := readDocxPart(f, parseXMLTree)
+		if err != nil {
+			return nil, err
+		}
+
+		title, body := pptxSlideText(slide)
+		var texts []string
+		if title != "" {
+			texts = append(texts, "# "+title)
+		}
+		texts = append(texts, body...)
+
+		if notesPath := pptxNotesPath(parts, slidePath); notesPath != "" {
+			notes, err := readDocxPart(parts[notesPath], parseXMLTree)
+			if err != nil {
+				return nil, err
+			}
+			if text := pptxNotesText(notes); text != "" {
+				texts = append(texts, "备注：\n"+text)
+			}
+		}
+
+		sectionTitle := fmt.Sprintf("第 %d 页", i+1)
+		if title != "" {
+			sectionTitle += "：" + title
+		}
+		sections = append(sections, Section{
+			Title:    sectionTitle,
+			Text:     strings.Join(texts, "\n\n"),
+			Metadata: map[string]interface{}{MetaSlide: i + 1},
+		})
+	}
+	return sections, nil
+}
+
+// pptxSlides 按演示文稿中的顺序列出幻灯片在压缩包中的路径
+func pptxSlides(parts map[string]*zip.File) ([]string, error) {
+	pres, ok := parts["ppt/presentation.xml"]
+	if !ok {
+		return nil, fmt.Errorf("无效的PPTX文件: 缺少 ppt/presentation.xml")
+	}
+	root, err := readDocxPart(pres, parseXMLTree)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := ooxmlRelationships(parts, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var slides []string
+	if list := root.find("sldIdLst"); list != nil {
+		for _, s := range list.findAll("sldId") {
+			if target, ok := targets[ooxmlRelationshipID(s)]; ok {
+				slides = append(slides, target.path)
+			}
+		}
+	}
+	return slides, nil
+}
+
+// ooxmlRelationshipID 元素上 r:id 属性的值，与同名的无命名空间 id 属性区分
+func ooxmlRelationshipID(n *xmlNode) string {
+	for _, attr := range n.Attr {
+		if attr.Name.Local == "id" && attr.Name.Space != "" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+type ooxmlRelationship struct {
+	path string // 压缩包中的完整路径
+	typ  string
+}
+
+// ooxmlRelationships 读取部件的关系文件（如 ppt/_rels/presentation.xml.rels），返回关系 ID -> 目标部件
+func ooxmlRelationships(parts map[string]*zip.File, part string) (map[string]ooxmlRelationship, error) {
+	dir, name := path.Split(part)
+	rels := map[string]ooxmlRelationship{}
+	f, ok := parts[dir+"_rels/"+name+".rels"]
+	if !ok {
+		return rels, nil
+	}
+	root, err := readDocxPart(f, parseXMLTree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range root.findAll("Relationship") {
+		target := rel.attr("Target")
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		rels[rel.attr("Id")] = ooxmlRelationship{path: target, typ: rel.attr("Type")}
+	}
+	return rels, nil
+}
+
+func pptxNotesPath(parts map[string]*zip.File, slidePath string) string {
+	rels, err := ooxmlRelationships(parts, slidePath)
+	if err != nil {
+		return ""
+	}
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.typ, "/notesSlide") {
+			if _, ok := parts[rel.path]; ok {
+				return rel.path
+			}
+		}
+	}
+	return ""
+}
+
+// pptxSlideText 返回标题占位符的文字和其余形状的文字，组合形状中的形状按顺序展开
+func pptxSlideText(slide *xmlNode) (string, []string) {
+	var title string
+	var body []string
+	tree := slide.find("spTree")
+	if tree == nil {
+		return "", nil
+	}
+
+	var walk func(*xmlNode)
+	walk = func(n *xmlNode) {
+		for _, c := range n.Children {
+			switch c.Name.Local {
+			case "sp":
+				text := pptxShapeText(c)
+				if text == "" {
+					continue
+				}
+				switch pptxPlaceholder(c) {
+				case "title", "ctrTitle":
+					if title == "" {
+						title = strings.ReplaceAll(text, "\n", " ")
+						continue
+					}
+				case "sldNum", "dt", "ftr":
+					// 页码、日期和页脚在每页重复出现
+					continue
+				}
+				body = append(body, text)
+			case "graphicFrame":
+				if tbl := c.find("tbl"); tbl != nil {
+					var rows []string
+					for _, tr := range tbl.findAll("tr") {
+						var cells []string
+						for _, tc := range tr.findAll("tc") {
+							cells = append(cells, strings.ReplaceAll(pptxShapeText(tc), "\n", " "))
+						}
+						rows = append(rows, strings.Join(cells, " | "))
+					}
+					body = append(body, strings.Join(rows, "\n"))
+				}
+			case "grpSp":
+				walk(c)
+			}
+		}
+	}
+	walk(tree)
+	return title, body
+}
+
+// pptxNotesText 备注页中正文占位符的文字，不含幻灯片缩略图和页码
+func pptxNotesText(notes *xmlNode) string {
+	var texts []string
+	for _, sp := range notes.findAll("sp") {
+		if pptxPlaceholder(sp) != "body" {
+			continue
+		}
+		if text := pptxShapeText(sp); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func pptxPlaceholder(sp *xmlNode) string {
+	if ph := sp.find("ph"); ph != nil {
+		if typ := ph.attr("type"); typ != "" {
+			return typ
+		}
+		return "body"
+	}
+	return ""
+}
+
+// pptxShapeText 形状中的段落，每段一行，多级项目符号按级别缩进
+func pptxShapeText(sp *xmlNode) string {
+	body := sp.find("txBody")
+	if body == nil {
+		return ""
+	}
+
+	var lines []string
+	for _, p := range body.findAll("p") {
+		var b strings.Builder
+		for _, c := range p.Children {
+			switch c.Name.Local {
+			case "r", "fld":
+				if t := c.find("t"); t != nil {
+					b.WriteString(t.text())
+				}
+			case "br":
+				b.WriteString("\n")
+			}
+		}
+		line := strings.TrimSpace(b.String())
+		if line == "" {
+			continue
+		}
+		if pPr := p.find("pPr"); pPr != nil {
+			if lvl, err := strconv.Atoi(pPr.attr("lvl")); err == nil && lvl > 0 {
+				line = strings.Repeat("  ", lvl) + line
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
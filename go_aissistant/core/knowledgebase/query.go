@@ -0,0 +1,153 @@
+package knowledgebase
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// 检索过滤用到的元数据
+const (
+	MetaFileType = "file_type" // 文件扩展名，小写并带点，如 ".pdf"
+	MetaTags     = "tags"      // 标签，逗号分隔的字符串或字符串数组
+)
+
+// 带过滤条件时向量库先多取若干倍的候选，再按条件筛选
+const queryOverfetch = 5
+
+// QueryOptions 知识库检索选项，各过滤条件为空时不限制，多个条件同时满足，同一条件的多个值满足其一即可
+type QueryOptions struct {
+	NumResults int
+
+	Sources   []string // 来源文件名（元数据 source）
+	DateFrom  string   // 导入日期下限，格式 2006-01-02，包含当天
+	DateTo    string   // 导入日期上限，包含当天
+	Tags      []string
+	FileTypes []string // 扩展名，如 pdf 或 .pdf
+
+	// Collections 检索的集合，只在 KnowledgeBaseManager.QueryCollections 中使用，为空时检索默认集合
+	Collections []string
+
+	// MinScore 最低相似度，低于该值的结果被丢弃，0 表示不限制；只作用于向量检索的结果
+	MinScore float32
+
+	// Mode 检索方式 vector、keyword 或 hybrid，为空时使用配置中的 retrieval_mode
+	Mode string
+}
+
+// Filtered 是否设置了元数据过滤条件
+func (o QueryOptions) Filtered() bool {
+	return len(o.Sources) > 0 || o.DateFrom != "" || o.DateTo != "" || len(o.Tags) > 0 || len(o.FileTypes) > 0
+}
+
+// fetchSize 向量检索时取的候选数
+func (o QueryOptions) fetchSize() int {
+	if o.Filtered() {
+		return o.NumResults * queryOverfetch
+	}
+	return o.NumResults
+}
+
+// Match 文档是否满足过滤条件和最低相似度
+func (o QueryOptions) Match(doc Document) bool {
+	if o.MinScore > 0 && doc.Score < o.MinScore {
+		return false
+	}
+
+	if len(o.Sources) > 0 {
+		source, _ := doc.Metadata["source"].(string)
+		if !containsFold(o.Sources, source) {
+			return false
+		}
+	}
+
+	if o.DateFrom != "" || o.DateTo != "" {
+		date, _ := doc.Metadata["date"].(string)
+		if date == "" || o.DateFrom != "" && date < o.DateFrom || o.DateTo != "" && date > o.DateTo {
+			return false
+		}
+	}
+
+	if len(o.FileTypes) > 0 && !containsFold(normalizeFileTypes(o.FileTypes), documentFileType(doc)) {
+		return false
+	}
+
+	if len(o.Tags) > 0 {
+		matched := false
+		for _, tag := range documentTags(doc) {
+			if containsFold(o.Tags, tag) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// filterDocuments 按条件筛选检索结果，最多保留 NumResults 条
+func (o QueryOptions) filterDocuments(docs []Document) []Document {
+	var result []Document
+	for _, doc := range docs {
+		if len(result) >= o.NumResults {
+			break
+		}
+		if o.Match(doc) {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// documentFileType 优先取元数据中的 file_type，旧文档按 path 或 source 的扩展名判断
+func documentFileType(doc Document) string {
+	if t, _ := doc.Metadata[MetaFileType].(string); t != "" {
+		return t
+	}
+	for _, key := range []string{MetaPath, "source"} {
+		if name, _ := doc.Metadata[key].(string); name != "" {
+			return strings.ToLower(filepath.Ext(name))
+		}
+	}
+	return ""
+}
+
+func documentTags(doc Document) []string {
+	switch v := doc.Metadata[MetaTags].(type) {
+	case string:
+		return ParsePatterns(v)
+	case []interface{}:
+		tags := make([]string, 0, len(v))
+		for _, t := range v {
+			if s, ok := t.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		return tags
+	case []string:
+		return v
+	}
+	return nil
+}
+
+func normalizeFileTypes(types []string) []string {
+	normalized := make([]string, len(types))
+	for i, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !strings.HasPrefix(t, ".") {
+			t = "." + t
+		}
+		normalized[i] = t
+	}
+	return normalized
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+)
+
+// LLM 重排时同时打分的候选数
+const defaultRerankWorkers = 4
+
+// 重排时每个候选最多发送给模型的字符数
+const rerankMaxChars = 1500
+
+// Reranker 对检索得到的候选重新打分排序
+type Reranker interface {
+	// Rerank 为每个候选设置 RerankScore（0-1），按得分从高到低返回
+	Rerank(ctx context.Context, query string, docs []Document) ([]Document, error)
+}
+
+// NewRerankerFromConfig 按 rerank_mode 创建重排器，未启用重排时返回 nil。
+// client 用于调用打分的大模型或向量模型，通常是按模型分发的 ai_model.Router
+func NewRerankerFromConfig(conf *config.AppConfig, client ai_model.Provider) (Reranker, error) {
+	switch conf.RerankMode {
+	case config.RerankNone:
+		return nil, nil
+	case config.RerankLLM:
+		model := conf.RerankModel
+		if model == "" {
+			model = conf.DefaultModel
+		}
+		return &LLMReranker{Client: client, Model: model, Workers: defaultRerankWorkers}, nil
+	case config.RerankEmbedding:
+		if conf.RerankModel == "" {
+			return nil, fmt.Errorf("rerank_mode 为 embedding 时需要配置 rerank_model")
+		}
+		return &EmbeddingReranker{Embedder: client, Model: conf.RerankModel}, nil
+	default:
+		return nil, fmt.Errorf("不支持的重排方式: %s", conf.RerankMode)
+	}
+}
+
+// RerankDocuments 重排候选后保留前 topK 条；reranker 为 nil 或重排失败时按原顺序保留
+func RerankDocuments(ctx context.Context, reranker Reranker, query string, docs []Document, topK int) []Document {
+	if reranker != nil && len(docs) > 0 {
+		reranked, err := reranker.Rerank(ctx, query, docs)
+		if err != nil {
+			log.Printf("重排失败，按检索顺序返回: %v", err)
+		} else {
+			docs = reranked
+		}
+	}
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs
+}
+
+// LLMReranker 让大模型逐条判断候选与问题的相关程度（0-10 分），适用于没有专门重排模型的情况
+type LLMReranker struct {
+	Client ai_model.Provider
+	Model  string
+	// Workers 同时打分的候选数
+	Workers int
+}
+
+var rerankScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+func (r *LLMReranker) Rerank(ctx context.Context, query string, docs []Document) ([]Document, error) {
+	result := append([]Document(nil), docs...)
+	errs := make([]error, len(result))
+
+	sem := make(chan struct{}, max(r.Workers, 1))
+	var wg sync.WaitGroup
+	for i := range result {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result[i].RerankScore, errs[i] = r.score(ctx, query, result[i].Text)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("重排候选 %s 失败: %v", result[i].ID, err)
+			failed++
+		}
+	}
+	if failed == len(result) {
+		return nil, errs[0]
+	}
+	sortByRerankScore(result)
+	return result, nil
+}
+
+func (r *LLMReranker) score(ctx context.Context, query, text string) (float32, error) {
+	if runes := []rune(text); len(runes) > rerankMaxChars {
+		text = string(runes[:rerankMaxChars])
+	}
+	prompt := fmt.Sprintf("请判断下面的文档片段对回答问题有多大帮助，用 0 到 10 的整数打分："+
+		"10 表示可以直接回答问题，0 表示完全无关。只输出分数，不要解释。\n\n问题：%s\n\n文档片段：\n%s", query, text)
+	reply, err := r.Client.Chat(ctx, r.Model, []ai_model.Message{
+		{Role: ai_model.RoleUser, Content: prompt},
+	}, &ai_model.Options{Temperature: ai_model.Float32(0), NumPredict: ai_model.Int(8)})
+	if err != nil {
+		return 0, err
+	}
+
+	match := rerankScorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("无法解析打分结果: %q", reply)
+	}
+	score, err := strconv.ParseFloat(match, 32)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析打分结果: %q", reply)
+	}
+	return float32(min(max(score, 0), 10) / 10), nil
+}
+
+// EmbeddingReranker 用另一个（通常更大、更准确的）向量模型重新计算候选与问题的余弦相似度，无需调用大模型
+type EmbeddingReranker struct {
+	Embedder Embedder
+	Model    string
+}
+
+func (r *EmbeddingReranker) Rerank(ctx context.Context, query string, docs []Document) ([]Document, error) {
+	texts := make([]string, 0, len(docs)+1)
+	texts = append(texts, query)
+	for _, doc := range docs {
+		texts = append(texts, doc.Text)
+	}
+	vectors, err := r.Embedder.Embed(ctx, r.Model, texts)
+	if err != nil {
+		return nil, fmt.Errorf("调用重排模型 %s 失败: %v", r.Model, err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("重排模型 %s 返回的向量数 %d 与输入数 %d 不一致", r.Model, len(vectors), len(texts))
+	}
+	for _, v := range vectors {
+		normalize(v)
+	}
+
+	result := append([]Document(nil), docs...)
+	for i := range result {
+		result[i].RerankScore = max(dot(vectors[0], vectors[i+1]), 0)
+	}
+	sortByRerankScore(result)
+	return result, nil
+}
+
+// sortByRerankScore 按重排得分从高到低排列，得分相同时保持检索顺序
+func sortByRerankScore(docs []Document) {
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].RerankScore > docs[j].RerankScore })
+}
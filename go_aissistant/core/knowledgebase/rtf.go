@@ -0,0 +1,308 @@
+package knowledgebase
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// 不输出内容的 RTF 目标组：字体表、样式表、文档信息、图片、页眉页脚、脚注等
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"object": true, "header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true, "footnote": true,
+	"listtable": true, "listoverridetable": true, "listtext": true, "pntext": true,
+	"rsidtbl": true, "generator": true, "themedata": true, "colorschememapping": true,
+	"latentstyles": true, "datastore": true, "fldinst": true, "filetbl": true,
+	"revtbl": true, "xmlnstbl": true, "mmathPr": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// 直接输出字符的控制字
+var rtfSymbols = map[string]string{
+	"par": "\n\n", "sect": "\n\n", "page": "\n\n", "line": "\n", "tab": "\t",
+	"cell": " | ", "row": "\n", "emdash": "—", "endash": "–", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+var rtfBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// rtfState 每个 {} 组的状态，进入组时继承外层
+type rtfState struct {
+	skip     bool   // 组内文字不输出
+	dest     string // 当前目标组的名称
+	uc       int    // \uN 之后需要跳过的替代字符数
+	codepage int    // \'hh 字节使用的代码页
+}
+
+// parseRTFFile 提取 RTF 文档正文：段落之间空一行，表格单元格以 " | " 分隔，
+// \'hh 按文档或字体声明的代码页（如 GBK）解码，\uN 直接输出 Unicode 字符
+func parseRTFFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(string(data), "{\\rtf") {
+		return "", fmt.Errorf("无效的RTF文件: 缺少 {\\rtf 文件头")
+	}
+
+	p := &rtfParser{data: data, codepage: 1252, fontCodepages: map[int]int{}}
+	p.state = rtfState{uc: 1, codepage: 1252}
+	p.parse()
+	text := strings.ReplaceAll(p.out.String(), " | \n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(rtfBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")), nil
+}
+
+type rtfParser struct {
+	data          []byte
+	pos           int
+	out           strings.Builder
+	state         rtfState
+	stack         []rtfState
+	pending       []byte      // 等待按代码页解码的 \'hh 字节
+	skipChars     int         // \uN 之后还要跳过的替代字符数
+	codepage      int         // 文档默认代码页（\ansicpg）
+	fontCodepages map[int]int // 字体 -> 代码页，0 表示使用文档默认代码页
+	font          int         // 字体表中正在定义的字体
+	groupStart    bool
+}
+
+func (p *rtfParser) parse() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch c {
+		case '{':
+			p.flush()
+			p.stack = append(p.stack, p.state)
+			p.groupStart = true
+			p.pos++
+			continue
+		case '}':
+			p.flush()
+			if len(p.stack) > 0 {
+				p.state = p.stack[len(p.stack)-1]
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			p.pos++
+		case '\\':
+			p.controlWord()
+		case '\r', '\n':
+			p.pos++
+		default:
+			p.flush()
+			p.pos++
+			if p.skipChars > 0 {
+				p.skipChars--
+			} else if !p.state.skip {
+				p.out.WriteByte(c)
+			}
+		}
+		p.groupStart = false
+	}
+	p.flush()
+}
+
+// controlWord 解析 \word[N] 或控制符号 \x
+func (p *rtfParser) controlWord() {
+	p.pos++ // 跳过反斜杠
+	if p.pos >= len(p.data) {
+		return
+	}
+
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		switch c {
+		case '\'':
+			if p.pos+2 <= len(p.data) {
+				if b, err := strconv.ParseUint(string(p.data[p.pos:p.pos+2]), 16, 8); err == nil {
+					if p.skipChars > 0 {
+						p.skipChars--
+					} else if !p.state.skip {
+						p.pending = append(p.pending, byte(b))
+					}
+				}
+				p.pos += 2
+			}
+			return
+		case '*':
+			// 无法识别时可以忽略的目标组
+			p.state.skip = true
+		case '{', '}', '\\':
+			p.text(string(c))
+		case '~':
+			p.text(" ")
+		case '_':
+			p.text("-")
+		case '\r', '\n':
+			p.text("\n\n")
+		}
+		return
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+
+	numStart := p.pos
+	if p.pos < len(p.data) && p.data[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	param, _ := strconv.Atoi(string(p.data[numStart:p.pos]))
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++ // 控制字后的空格是分隔符
+	}
+
+	groupStart := p.groupStart
+	p.groupStart = false
+	if rtfSkippedDestinations[word] && groupStart || word == "fonttbl" {
+		p.flush()
+		p.state.skip = true
+		p.state.dest = word
+		return
+	}
+
+	switch word {
+	case "u":
+		if param < 0 {
+			param += 65536
+		}
+		p.text(string(rune(param)))
+		p.skipChars = p.state.uc
+	case "uc":
+		p.state.uc = param
+	case "ansicpg":
+		p.codepage = param
+		p.state.codepage = param
+	case "f":
+		if p.state.dest == "fonttbl" {
+			p.font = param
+		} else if cp, ok := p.fontCodepages[param]; ok {
+			if cp == 0 {
+				cp = p.codepage
+			}
+			p.flush()
+			p.state.codepage = cp
+		}
+	case "fcharset":
+		if p.state.dest == "fonttbl" {
+			p.fontCodepages[p.font] = rtfCharsetCodepage(param)
+		}
+	case "bin":
+		// 二进制数据直接跳过
+		p.pos += max(param, 0)
+	default:
+		if s, ok := rtfSymbols[word]; ok {
+			p.text(s)
+		}
+	}
+}
+
+func (p *rtfParser) text(s string) {
+	p.flush()
+	if p.skipChars > 0 {
+		p.skipChars--
+		return
+	}
+	if !p.state.skip {
+		p.out.WriteString(s)
+	}
+}
+
+// flush 按当前代码页解码累积的 \'hh 字节
+func (p *rtfParser) flush() {
+	if len(p.pending) == 0 {
+		return
+	}
+	decoded, err := rtfEncoding(p.state.codepage).NewDecoder().Bytes(p.pending)
+	if err != nil {
+		decoded = p.pending
+	}
+	p.out.Write(decoded)
+	p.pending = p.pending[:0]
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// rtfCharsetCodepage 字体字符集（\fcharsetN）对应的代码页，ANSI 等返回 0
+func rtfCharsetCodepage(charset int) int {
+	switch charset {
+	case 128:
+		return 932
+	case 129:
+		return 949
+	case 134:
+		return 936
+	case 136:
+		return 950
+	case 161:
+		return 1253
+	case 162:
+		return 1254
+	case 177:
+		return 1255
+	case 178:
+		return 1256
+	case 186:
+		return 1257
+	case 204:
+		return 1251
+	case 238:
+		return 1250
+	}
+	return 0
+}
+
+func rtfEncoding(codepage int) encoding.Encoding {
+	switch codepage {
+	case 936:
+		return simplifiedchinese.GBK
+	case 950:
+		return traditionalchinese.Big5
+	case 932:
+		return japanese.ShiftJIS
+	case 949:
+		return korean.EUCKR
+	case 874:
+		return charmap.Windows874
+	case 1250:
+		return charmap.Windows1250
+	case 1251:
+		return charmap.Windows1251
+	case 1253:
+		return charmap.Windows1253
+	case 1254:
+		return charmap.Windows1254
+	case 1255:
+		return charmap.Windows1255
+	case 1256:
+		return charmap.Windows1256
+	case 1257:
+		return charmap.Windows1257
+	case 1258:
+		return charmap.Windows1258
+	case 10000:
+		return charmap.Macintosh
+	}
+	return charmap.Windows1252
+}
@@ -0,0 +1,487 @@
+package knowledgebase
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 表格数据片段的元数据键
+const (
+	MetaRow   = "row"
+	MetaSheet = "sheet"
+)
+
+// 列名与这些元数据键冲突时加 col_ 前缀，避免覆盖来源等公共元数据
+var reservedMetaKeys = map[string]bool{
+	"source": true, "date": true, MetaPath: true, MetaRow: true, MetaSheet: true,
+	MetaSection: true, MetaParentID: true, MetaChunkIndex: true, MetaChunkCount: true,
+	MetaStartOffset: true, MetaEndOffset: true, MetaHeading: true, MetaCollection: true,
+	MetaHash: true, MetaModTime: true, MetaFileType: true,
+}
+
+// rowRenderer 把一行数据渲染为文档文本：配置了模板时按模板渲染，否则逐列输出 "列名: 值"
+type rowRenderer struct {
+	tmpl *template.Template
+}
+
+// newRowRenderer 模板使用 text/template 语法，数据为列名到值的映射，
+// 如 "{{.SKU}} 的价格是 {{.price}}"，列名不是合法标识符时用 {{index . "商品名称"}}
+func newRowRenderer(text string) (*rowRenderer, error) {
+	if strings.TrimSpace(text) == "" {
+		return &rowRenderer{}, nil
+	}
+	tmpl, err := template.New("row").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析行模板失败: %v", err)
+	}
+	return &rowRenderer{tmpl: tmpl}, nil
+}
+
+func (r *rowRenderer) render(columns []string, values map[string]string) (string, error) {
+	if r.tmpl != nil {
+		var b strings.Builder
+		if err := r.tmpl.Execute(&b, values); err != nil {
+			return "", fmt.Errorf("渲染行模板失败: %v", err)
+		}
+		return b.String(), nil
+	}
+
+	var lines []string
+	for _, col := range columns {
+		if v := values[col]; v != "" {
+			lines = append(lines, col+": "+v)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// rowSection 构造一行数据的片段，列值写入元数据
+func (r *rowRenderer) rowSection(title string, columns []string, values map[string]string, metadata map[string]interface{}) (Section, bool, error) {
+	text, err := r.render(columns, values)
+	if err != nil {
+		return Section{}, false, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return Section{}, false, nil
+	}
+
+	for _, col := range columns {
+		if values[col] == "" {
+			continue
+		}
+		key := col
+		if reservedMetaKeys[key] {
+			key = "col_" + key
+		}
+		metadata[key] = values[col]
+	}
+	return Section{Title: title, Text: text, Metadata: metadata}, true, nil
+}
+
+// tableSections 第一行为表头，之后每个非空行生成一个片段
+func (r *rowRenderer) tableSections(rows [][]string, sheet string) ([]Section, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	// 表头比数据行短时，多出的列以 "列N" 命名
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	header := make([]string, width)
+	seen := map[string]bool{}
+	for i := range header {
+		h := ""
+		if i < len(rows[0]) {
+			h = strings.TrimSpace(strings.TrimPrefix(rows[0][i], "\ufeff"))
+		}
+		if h == "" || seen[h] {
+			h = fmt.Sprintf("列%d", i+1)
+		}
+		seen[h] = true
+		header[i] = h
+	}
+
+	var sections []Section
+	for i, row := range rows[1:] {
+		values := make(map[string]string, len(header))
+		for j, col := range header {
+			if j < len(row) {
+				values[col] = strings.TrimSpace(row[j])
+			}
+		}
+
+		rowNo := i + 2 // 与表格软件中的行号一致
+		title := fmt.Sprintf("第 %d 行", rowNo)
+		metadata := map[string]interface{}{MetaRow: rowNo}
+		if sheet != "" {
+			title = sheet + " " + title
+			metadata[MetaSheet] = sheet
+		}
+		s, ok, err := r.rowSection(title, header, values, metadata)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sections = append(sections, s)
+		}
+	}
+	return sections, nil
+}
+
+// parseCSVSections 解析 CSV/TSV，分隔符根据首行自动识别
+func parseCSVSections(filePath string, r *rowRenderer) ([]Section, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = sniffDelimiter(content)
+	if strings.EqualFold(path.Ext(filePath), ".tsv") {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV失败: %v", err)
+	}
+	return r.tableSections(rows, "")
+}
+
+func sniffDelimiter(content []byte) rune {
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	best, count := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if n := bytes.Count(firstLine, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// parseJSONSections 解析 JSON 或 JSONL：数组中的每个元素、JSONL 的每一行为一条记录，
+// 嵌套对象展开为 "a.b" 形式的列名
+func parseJSONSections(filePath string, r *rowRenderer) ([]Section, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []interface{}
+	if strings.EqualFold(path.Ext(filePath), ".jsonl") {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var record interface{}
+			if err := json.Unmarshal([]byte(text), &record); err != nil {
+				return nil, fmt.Errorf("解析JSONL第 %d 行失败: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取JSONL失败: %v", err)
+		}
+	} else {
+		var data interface{}
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %v", err)
+		}
+		if list, ok := data.([]interface{}); ok {
+			records = list
+		} else {
+			records = []interface{}{data}
+		}
+	}
+
+	var sections []Section
+	for i, record := range records {
+		values := map[string]string{}
+		flattenJSON("", record, values)
+		columns := make([]string, 0, len(values))
+		for col := range values {
+			columns = append(columns, col)
+		}
+		sort.Strings(columns)
+
+		s, ok, err := r.rowSection(fmt.Sprintf("第 %d 条", i+1), columns, values, map[string]interface{}{MetaRow: i + 1})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sections = append(sections, s)
+		}
+	}
+	return sections, nil
+}
+
+// flattenJSON 把嵌套对象展开为扁平的键值，标量数组以 ", " 连接，其他数组保留 JSON 文本
+func flattenJSON(prefix string, v interface{}, out map[string]string) {
+	key := prefix
+	if key == "" {
+		key = "value"
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flattenJSON(k, child, out)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				data, _ := json.Marshal(val)
+				out[key] = string(data)
+				return
+			}
+			parts = append(parts, jsonScalar(item))
+		}
+		out[key] = strings.Join(parts, ", ")
+	default:
+		out[key] = jsonScalar(val)
+	}
+}
+
+func jsonScalar(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// parseXLSXSections 直接读取 OOXML 表格：每个工作表第一行为表头，其余每行一个片段
+func parseXLSXSections(filePath string, r *rowRenderer) ([]Section, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开XLSX文件失败: %v", err)
+	}
+	defer zr.Close()
+
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := parts["xl/sharedStrings.xml"]; ok {
+		if shared, err = readDocxPart(f, parseSharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	sheets, err := xlsxSheets(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for _, sheet := range sheets {
+		f, ok := parts[sheet.path]
+		if !ok {
+			continue
+		}
+		rows, err := readDocxPart(f, func(rd io.Reader) ([][]string, error) {
+			return parseSheetRows(rd, shared)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, err := r.tableSections(rows, sheet.name)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s...)
+	}
+	return sections, nil
+}
+
+type xlsxSheet struct {
+	name string
+	path string
+}
+
+// xlsxSheets 按工作簿中的顺序列出工作表及其在压缩包中的路径
+func xlsxSheets(parts map[string]*zip.File) ([]xlsxSheet, error) {
+	wb, ok := parts["xl/workbook.xml"]
+	if !ok {
+		return nil, fmt.Errorf("无效的XLSX文件: 缺少 xl/workbook.xml")
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if _, err := readDocxPart(wb, func(r io.Reader) (bool, error) {
+		return true, xml.NewDecoder(r).Decode(&workbook)
+	}); err != nil {
+		return nil, err
+	}
+
+	targets := map[string]string{}
+	if rels, ok := parts["xl/_rels/workbook.xml.rels"]; ok {
+		var relationships struct {
+			Items []struct {
+				ID     string `xml:"Id,attr"`
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if _, err := readDocxPart(rels, func(r io.Reader) (bool, error) {
+			return true, xml.NewDecoder(r).Decode(&relationships)
+		}); err != nil {
+			return nil, err
+		}
+		for _, rel := range relationships.Items {
+			target := strings.TrimPrefix(rel.Target, "/")
+			if !strings.HasPrefix(target, "xl/") {
+				target = path.Join("xl", target)
+			}
+			targets[rel.ID] = target
+		}
+	}
+
+	var sheets []xlsxSheet
+	for i, s := range workbook.Sheets {
+		sheetPath := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		for _, attr := range s.Attr {
+			if attr.Name.Local == "id" {
+				if target, ok := targets[attr.Value]; ok {
+					sheetPath = target
+				}
+			}
+		}
+		sheets = append(sheets, xlsxSheet{name: s.Name, path: sheetPath})
+	}
+	return sheets, nil
+}
+
+// parseSharedStrings 共享字符串表，富文本由多个 <r><t> 组成
+func parseSharedStrings(r io.Reader) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			T    string `xml:"t"`
+			Runs []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.NewDecoder(r).Decode(&sst); err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		text := si.T
+		for _, run := range si.Runs {
+			text += run.T
+		}
+		strs[i] = text
+	}
+	return strs, nil
+}
+
+// parseSheetRows 读取工作表中的单元格，按单元格引用（如 C3）放到对应的行列位置
+func parseSheetRows(r io.Reader, shared []string) ([][]string, error) {
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					T    string `xml:"t"`
+					Runs []struct {
+						T string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.NewDecoder(r).Decode(&sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = xlsxColumn(c.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch c.Type {
+			case "s":
+				if idx, err := strconv.Atoi(c.Value); err == nil && idx < len(shared) {
+					values[col] = shared[idx]
+				}
+			case "inlineStr":
+				text := c.Inline.T
+				for _, run := range c.Inline.Runs {
+					text += run.T
+				}
+				values[col] = text
+			case "b":
+				values[col] = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			default:
+				values[col] = c.Value
+			}
+		}
+
+		// 跳过的空行补齐，使行号与表格一致
+		for row.R > 0 && len(rows) < row.R-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// xlsxColumn 单元格引用中的列号，从 0 开始（A -> 0, AA -> 26）
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col - 1
+}
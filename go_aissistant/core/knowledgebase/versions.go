@@ -0,0 +1,172 @@
+package knowledgebase
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+// SourceVersion 来源文件的一次导入
+type SourceVersion struct {
+	Version   int
+	Hash      string
+	Chunks    int
+	Note      string
+	CreatedAt time.Time
+}
+
+// VersionStore 记录每个来源文件的导入历史，保存各版本切分后的块（不含向量），以便查看和回滚。
+// 与向量库类型无关，统一保存在 chroma_path 目录下的 knowledge.db 中
+type VersionStore struct {
+	db *sql.DB
+}
+
+func NewVersionStore(conf *config.AppConfig) (*VersionStore, error) {
+	db, err := openLocalKBFile(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	createTableSQL := `
+    CREATE TABLE IF NOT EXISTS kb_versions (
+        collection TEXT NOT NULL,
+        source TEXT NOT NULL,
+        version INTEGER NOT NULL,
+        hash TEXT NOT NULL,
+        chunks INTEGER NOT NULL,
+        documents TEXT NOT NULL,
+        note TEXT NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (collection, source, version)
+    );
+    `
+	if _, err := db.Exec(createTableSQL); err != nil {
+		log.Printf("创建版本表失败: %v", err)
+		return nil, fmt.Errorf("创建版本表失败: %v", err)
+	}
+	return &VersionStore{db: db}, nil
+}
+
+// Record 记录来源文件的新版本，返回版本号。内容哈希与最新版本相同时不重复记录，返回最新版本号
+func (vs *VersionStore) Record(collection, source, hash string, docs []Document, note string) (int, error) {
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var latest int
+	var latestHash string
+	err = tx.QueryRow(`SELECT version, hash FROM kb_versions WHERE collection = ? AND source = ? ORDER BY version DESC LIMIT 1`,
+		collection, source).Scan(&latest, &latestHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("读取版本失败: %v", err)
+	}
+	if latest > 0 && latestHash == hash {
+		return latest, nil
+	}
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return 0, fmt.Errorf("序列化文档失败: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO kb_versions(collection, source, version, hash, chunks, documents, note) VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		collection, source, latest+1, hash, len(docs), string(data), note); err != nil {
+		return 0, fmt.Errorf("写入版本失败: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return latest + 1, nil
+}
+
+// History 列出来源文件的全部版本，最新的在前
+func (vs *VersionStore) History(collection, source string) ([]SourceVersion, error) {
+	rows, err := vs.db.Query(`
+        SELECT version, hash, chunks, note, created_at FROM kb_versions
+        WHERE collection = ? AND source = ? ORDER BY version DESC
+    `, collection, source)
+	if err != nil {
+		return nil, fmt.Errorf("查询版本失败: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []SourceVersion
+	for rows.Next() {
+		var v SourceVersion
+		if err := rows.Scan(&v.Version, &v.Hash, &v.Chunks, &v.Note, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描行失败: %v", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// Documents 读取某个版本导入的块
+func (vs *VersionStore) Documents(collection, source string, version int) ([]Document, error) {
+	var data string
+	err := vs.db.QueryRow(`SELECT documents FROM kb_versions WHERE collection = ? AND source = ? AND version = ?`,
+		collection, source, version).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("版本不存在: %s 版本 %d", source, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取版本失败: %v", err)
+	}
+
+	var docs []Document
+	if err := json.Unmarshal([]byte(data), &docs); err != nil {
+		return nil, fmt.Errorf("解析版本文档失败: %v", err)
+	}
+	return docs, nil
+}
+
+// Rollback 用指定版本的块替换知识库中该来源的块：先写入该版本的块，再删除其余的块，并把回滚记录为一个新版本。
+// 块中记录的修改时间改为磁盘上文件当前的修改时间，监听该文件的 FolderWatcher 启动时不会因文件较新而重新导入；
+// 文件之后再被修改时仍会重新导入，回滚随之失效
+func (vs *VersionStore) Rollback(kb KnowledgeBaseI, source string, version int) error {
+	docs, err := vs.Documents(kb.Name(), source, version)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("版本 %d 中没有文档", version)
+	}
+	if info, err := os.Stat(source); err == nil {
+		for i := range docs {
+			if docs[i].Metadata == nil {
+				docs[i].Metadata = map[string]interface{}{}
+			}
+			docs[i].Metadata[MetaModTime] = info.ModTime().UnixMilli()
+		}
+	}
+	if err := kb.AddDocuments(docs); err != nil {
+		return err
+	}
+	if err := kb.DeleteBySource(source, documentIDs(docs)...); err != nil {
+		return err
+	}
+
+	hash, _ := docs[0].Metadata[MetaHash].(string)
+	_, err = vs.Record(kb.Name(), source, hash, docs, fmt.Sprintf("回滚到版本 %d", version))
+	return err
+}
+
+func (vs *VersionStore) renameCollection(oldName, newName string) error {
+	if _, err := vs.db.Exec(`UPDATE kb_versions SET collection = ? WHERE collection = ?`, newName, oldName); err != nil {
+		return fmt.Errorf("更新版本记录失败: %v", err)
+	}
+	return nil
+}
+
+func (vs *VersionStore) dropCollection(name string) error {
+	if _, err := vs.db.Exec(`DELETE FROM kb_versions WHERE collection = ?`, name); err != nil {
+		return fmt.Errorf("删除版本记录失败: %v", err)
+	}
+	return nil
+}
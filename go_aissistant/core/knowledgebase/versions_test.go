@@ -0,0 +1,79 @@
+package knowledgebase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fighthorse/aicode/go_aissistant/config"
+)
+
+func TestRollbackSurvivesFolderSync(t *testing.T) {
+	kb := newTestLocalKB(t)
+	conf := &config.AppConfig{ChromaPath: t.TempDir()}
+	versions, err := NewVersionStore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { versions.db.Close() })
+	importer := NewImporterFromConfig(conf)
+	importer.Versions = versions
+
+	folder := t.TempDir()
+	path := filepath.Join(folder, "a.txt")
+	modTime := time.Now().Add(-time.Hour)
+	write := func(text string) {
+		t.Helper()
+		modTime = modTime.Add(time.Minute)
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts := func() []string {
+		t.Helper()
+		docs, err := kb.ListDocuments()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, d := range docs {
+			texts = append(texts, d.Text)
+		}
+		return texts
+	}
+	sync := func() {
+		t.Helper()
+		watcher, err := NewFolderWatcher(importer, kb, folder, FolderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := watcher.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("version one")
+	sync()
+	write("version two")
+	sync()
+	if err := versions.Rollback(kb, path, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件未再修改，启动时同步不应覆盖回滚
+	sync()
+	if got := texts(); len(got) != 1 || got[0] != "version one" {
+		t.Errorf("回滚后同步，知识库中为 %q", got)
+	}
+
+	write("version three")
+	sync()
+	if got := texts(); len(got) != 1 || got[0] != "version three" {
+		t.Errorf("文件修改后同步，知识库中为 %q", got)
+	}
+}
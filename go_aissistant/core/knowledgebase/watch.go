@@ -0,0 +1,340 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 文件同步的动作
+const (
+	SyncAdded   = "新增"
+	SyncUpdated = "更新"
+	SyncRemoved = "删除"
+)
+
+// 保存文件、git checkout 等操作会连续产生多个事件，安静一段时间后再统一同步
+const watchDebounce = 500 * time.Millisecond
+
+// SyncEvent 一个文件的同步结果
+type SyncEvent struct {
+	Path   string
+	Action string
+	Chunks int
+	Err    error
+}
+
+type syncState struct {
+	hash    string
+	modTime int64
+}
+
+// FolderWatcher 监听文件夹，把新增、修改和删除的文件增量同步到知识库。
+// 文件状态取自知识库中块的元数据（path、hash、mtime），不需要额外保存状态：
+// 启动时修改时间未变的文件直接跳过，内容哈希相同的文件不会重新向量化
+type FolderWatcher struct {
+	importer *Importer
+	kb       KnowledgeBaseI
+	root     string
+	filter   *folderFilter
+
+	// OnSync 每同步一个文件调用一次，可能来自不同协程
+	OnSync func(SyncEvent)
+
+	mu    sync.Mutex
+	files map[string]syncState // 已导入文件的绝对路径 -> 状态
+}
+
+func NewFolderWatcher(importer *Importer, kb KnowledgeBaseI, root string, opts FolderOptions) (*FolderWatcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("解析路径失败: %v", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件夹失败: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("不是文件夹: %s", root)
+	}
+	filter, err := importer.newFolderFilter(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FolderWatcher{
+		importer: importer,
+		kb:       kb,
+		root:     root,
+		filter:   filter,
+		files:    map[string]syncState{},
+	}, nil
+}
+
+// Root 监听的文件夹的绝对路径
+func (w *FolderWatcher) Root() string {
+	return w.root
+}
+
+// Sync 对比文件夹与知识库：导入新增和内容变化的文件，删除已不存在的文件的块
+func (w *FolderWatcher) Sync(ctx context.Context) error {
+	if err := w.loadState(); err != nil {
+		return err
+	}
+	files, err := w.filter.walk(w.root, nil)
+	if err != nil {
+		return err
+	}
+	err = forEach(ctx, w.importer.Workers, files, func(path string) {
+		w.syncFile(path, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, path := range files {
+		present[path] = true
+	}
+	w.mu.Lock()
+	var stale []string
+	for path := range w.files {
+		if !present[path] {
+			stale = append(stale, path)
+		}
+	}
+	w.mu.Unlock()
+	sort.Strings(stale)
+	for _, path := range stale {
+		w.removePath(path)
+	}
+	return nil
+}
+
+// Run 先同步一次，然后监听文件变化直到 ctx 取消
+func (w *FolderWatcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监听失败: %v", err)
+	}
+	defer watcher.Close()
+
+	// 先注册监听再同步，同步期间发生的变化随后处理
+	if _, err := w.watchTree(watcher, w.root); err != nil {
+		return err
+	}
+	if err := w.Sync(ctx); err != nil {
+		return err
+	}
+
+	pending := map[string]bool{}
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					// 新目录需要单独注册监听，注册前已经写入的文件在这里补上
+					files, err := w.watchTree(watcher, ev.Name)
+					if err != nil {
+						log.Printf("监听目录 %s 失败: %v", ev.Name, err)
+					}
+					for _, path := range files {
+						pending[path] = true
+					}
+					timer.Reset(watchDebounce)
+					continue
+				}
+			}
+			pending[ev.Name] = true
+			timer.Reset(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("监听文件夹 %s 出错: %v", w.root, err)
+
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			pending = map[string]bool{}
+			sort.Strings(paths)
+			if err := forEach(ctx, w.importer.Workers, paths, w.syncPath); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchTree 监听 dir 及其未被排除的子目录，返回其中需要导入的文件
+func (w *FolderWatcher) watchTree(watcher *fsnotify.Watcher, dir string) ([]string, error) {
+	if !w.filter.dir(dir) {
+		return nil, nil
+	}
+	return w.filter.walk(dir, func(path string) error {
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("监听目录 %s 失败: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// loadState 从知识库中已有块的元数据恢复文件状态
+func (w *FolderWatcher) loadState() error {
+	docs, err := w.kb.ListDocuments()
+	if err != nil {
+		return err
+	}
+
+	files := map[string]syncState{}
+	for _, doc := range docs {
+		path, _ := doc.Metadata[MetaPath].(string)
+		if path == "" {
+			continue
+		}
+		if _, ok := w.filter.rel(path); !ok {
+			continue
+		}
+		hash, _ := doc.Metadata[MetaHash].(string)
+		files[path] = syncState{hash: hash, modTime: metaInt(doc.Metadata[MetaModTime])}
+	}
+
+	w.mu.Lock()
+	w.files = files
+	w.mu.Unlock()
+	return nil
+}
+
+// syncPath 处理一个发生变化的路径：不存在时删除对应的块（文件或整个目录），否则同步文件
+func (w *FolderWatcher) syncPath(path string) {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		w.removePath(path)
+	case err != nil:
+		log.Printf("读取文件信息失败: %v", err)
+	case info.Mode().IsRegular() && w.filter.contains(path):
+		w.syncFile(path, false)
+	}
+}
+
+// syncFile 文件内容变化时先解析切分，成功后再替换旧块。
+// trustModTime 为 true 时修改时间未变的文件直接跳过；监听到变化的文件总是比较哈希，
+// 因为同一毫秒内的两次写入修改时间相同
+func (w *FolderWatcher) syncFile(path string, trustModTime bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.removePath(path)
+		}
+		return
+	}
+
+	w.mu.Lock()
+	old, known := w.files[path]
+	w.mu.Unlock()
+	if trustModTime && known && old.hash != "" && old.modTime == info.ModTime().UnixMilli() {
+		return
+	}
+
+	action := SyncAdded
+	if known {
+		action = SyncUpdated
+	}
+	hash, modTime, err := fileState(path)
+	if err != nil {
+		w.report(SyncEvent{Path: path, Action: action, Err: err})
+		return
+	}
+	if known && old.hash == hash {
+		// 只有修改时间变化，如 touch 或切换分支后内容相同
+		w.setState(path, syncState{hash: hash, modTime: modTime})
+		return
+	}
+
+	chunks, err := w.importer.FileDocuments(path)
+	if err == nil && len(chunks) == 0 {
+		err = fmt.Errorf("文件中没有可导入的文本: %s", filepath.Base(path))
+	}
+	if err == nil {
+		err = w.importer.replaceSource(w.kb, chunks)
+	}
+	if err != nil {
+		w.report(SyncEvent{Path: path, Action: action, Err: err})
+		return
+	}
+	w.setState(path, syncState{hash: hash, modTime: modTime})
+	w.report(SyncEvent{Path: path, Action: action, Chunks: len(chunks)})
+}
+
+// removePath 删除路径对应文件的块，路径是目录时删除其下全部已导入文件的块
+func (w *FolderWatcher) removePath(path string) {
+	w.mu.Lock()
+	var removed []string
+	for p := range w.files {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			removed = append(removed, p)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, p := range removed {
+		if err := w.kb.DeleteBySource(p); err != nil {
+			w.report(SyncEvent{Path: p, Action: SyncRemoved, Err: err})
+			continue
+		}
+		w.mu.Lock()
+		delete(w.files, p)
+		w.mu.Unlock()
+		w.report(SyncEvent{Path: p, Action: SyncRemoved})
+	}
+}
+
+func (w *FolderWatcher) setState(path string, state syncState) {
+	w.mu.Lock()
+	w.files[path] = state
+	w.mu.Unlock()
+}
+
+func (w *FolderWatcher) report(ev SyncEvent) {
+	if ev.Err != nil {
+		log.Printf("同步文件 %s 失败: %v", ev.Path, ev.Err)
+	}
+	if w.OnSync != nil {
+		w.OnSync(ev)
+	}
+}
+
+// metaInt 读取元数据中的整数，经 JSON 或 Chroma 往返后可能是 float64、float32 或 int
+func metaInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float32:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package knowledgebase
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// xmlNode 保留文本与子元素先后顺序的 XML 树，用于 ODT、PPTX 等按文档顺序提取文字的格式
+type xmlNode struct {
+	Name     xml.Name
+	Attr     []xml.Attr
+	Children []*xmlNode
+	Text     string // 文本节点的内容，元素节点为空
+}
+
+// parseXMLTree 解析整个 XML 文档，返回虚拟的根节点
+func parseXMLTree(r io.Reader) (*xmlNode, error) {
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{Name: t.Name, Attr: t.Attr}
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.Children = append(parent.Children, &xmlNode{Text: string(t)})
+		}
+	}
+}
+
+func (n *xmlNode) isText() bool {
+	return n.Name.Local == ""
+}
+
+func (n *xmlNode) attr(local string) string {
+	return xmlAttr(xml.StartElement{Attr: n.Attr}, local)
+}
+
+// find 按文档顺序查找第一个指定名称的后代元素
+func (n *xmlNode) find(local string) *xmlNode {
+	for _, c := range n.Children {
+		if c.Name.Local == local {
+			return c
+		}
+		if found := c.find(local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll 查找全部指定名称的后代元素，不进入已匹配元素的子树
+func (n *xmlNode) findAll(local string) []*xmlNode {
+	var nodes []*xmlNode
+	for _, c := range n.Children {
+		if c.Name.Local == local {
+			nodes = append(nodes, c)
+			continue
+		}
+		nodes = append(nodes, c.findAll(local)...)
+	}
+	return nodes
+}
+
+// text 子树中的全部文字
+func (n *xmlNode) text() string {
+	var b strings.Builder
+	var collect func(*xmlNode)
+	collect = func(n *xmlNode) {
+		b.WriteString(n.Text)
+		for _, c := range n.Children {
+			collect(c)
+		}
+	}
+	collect(n)
+	return b.String()
+}
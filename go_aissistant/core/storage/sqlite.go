@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -9,6 +10,39 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// 引用来源的类型
+const (
+	CitationKnowledge = "knowledge" // 知识库中的文档块
+	CitationWeb       = "web"       // 网络搜索结果
+)
+
+// Citation 回答中 [n] 对应的参考来源，随对话记录一起保存
+type Citation struct {
+	Index int    `json:"index"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	// Path 知识库文档的原始文件路径，Page 为 PDF 等分页文档的页码，没有页码时为 0
+	Path string `json:"path,omitempty"`
+	Page int    `json:"page,omitempty"`
+	// URL 网络搜索结果的链接
+	URL     string  `json:"url,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
+	Score   float32 `json:"score,omitempty"`
+}
+
+// ParseCitations 解析保存的引用列表，旧记录或格式错误时返回空
+func ParseCitations(data string) []Citation {
+	if data == "" {
+		return nil
+	}
+	var citations []Citation
+	if err := json.Unmarshal([]byte(data), &citations); err != nil {
+		log.Printf("解析引用失败: %v", err)
+		return nil
+	}
+	return citations
+}
+
 type SQLiteStorage struct {
 	db        *sql.DB
 	batchSize int
@@ -49,6 +83,10 @@ func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
 		log.Printf("创建表失败: %v", err)
 		return nil, fmt.Errorf("创建表失败: %v", err)
 	}
+	if err := migrate(db); err != nil {
+		log.Printf("升级表结构失败: %v", err)
+		return nil, err
+	}
 
 	return &SQLiteStorage{
 		db:        db,
@@ -56,7 +94,44 @@ func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
 	}, nil
 }
 
-func (s *SQLiteStorage) SaveChatRecord(query, response, model string) error {
+// migrate 为旧版本创建的表补充新增的列
+func migrate(db *sql.DB) error {
+	rows, err := db.Query(`PRAGMA table_info(chat_history)`)
+	if err != nil {
+		return fmt.Errorf("读取表结构失败: %v", err)
+	}
+	columns := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取表结构失败: %v", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	if !columns["citations"] {
+		if _, err := db.Exec(`ALTER TABLE chat_history ADD COLUMN citations TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("添加 citations 列失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// SaveChatRecord 保存一轮问答及回答中引用的来源
+func (s *SQLiteStorage) SaveChatRecord(query, response, model string, citations []Citation) error {
+	data := ""
+	if len(citations) > 0 {
+		b, err := json.Marshal(citations)
+		if err != nil {
+			return fmt.Errorf("序列化引用失败: %v", err)
+		}
+		data = string(b)
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		log.Printf("开始事务失败: %v", err)
@@ -65,8 +140,8 @@ func (s *SQLiteStorage) SaveChatRecord(query, response, model string) error {
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(`
-        INSERT INTO chat_history(query, response, model) 
-        VALUES(?, ?, ?)
+        INSERT INTO chat_history(query, response, model, citations)
+        VALUES(?, ?, ?, ?)
     `)
 	if err != nil {
 		log.Printf("准备语句失败: %v", err)
@@ -74,7 +149,7 @@ func (s *SQLiteStorage) SaveChatRecord(query, response, model string) error {
 	}
 	defer stmt.Close()
 
-	if _, err := stmt.Exec(query, response, model); err != nil {
+	if _, err := stmt.Exec(query, response, model, data); err != nil {
 		log.Printf("执行插入语句失败: %v", err)
 		return fmt.Errorf("执行插入语句失败: %v", err)
 	}
@@ -89,7 +164,7 @@ func (s *SQLiteStorage) SaveChatRecord(query, response, model string) error {
 
 func (s *SQLiteStorage) GetRecentHistory(num int64) ([]map[string]string, error) {
 	rows, err := s.db.Query(`
-        SELECT id, query, response, model, timestamp
+        SELECT id, query, response, model, citations, timestamp
         FROM chat_history
         ORDER BY timestamp DESC
         LIMIT ?
@@ -104,8 +179,8 @@ func (s *SQLiteStorage) GetRecentHistory(num int64) ([]map[string]string, error)
 	var history []map[string]string
 	for rows.Next() {
 		var id int
-		var query, response, model, timestamp string
-		if err := rows.Scan(&id, &query, &response, &model, &timestamp); err != nil {
+		var query, response, model, citations, timestamp string
+		if err := rows.Scan(&id, &query, &response, &model, &citations, &timestamp); err != nil {
 			log.Printf("扫描行失败: %v", err)
 			return nil, fmt.Errorf("扫描行失败: %v", err)
 		}
@@ -114,6 +189,7 @@ func (s *SQLiteStorage) GetRecentHistory(num int64) ([]map[string]string, error)
 			"query":     query,
 			"response":  response,
 			"model":     model,
+			"citations": citations,
 			"timestamp": timestamp,
 		})
 	}
@@ -128,7 +204,7 @@ func (s *SQLiteStorage) GetRecentHistory(num int64) ([]map[string]string, error)
 
 func (s *SQLiteStorage) GetHistoryByTimeRange(start, end time.Time) ([]map[string]interface{}, error) {
 	rows, err := s.db.Query(`
-        SELECT id, query, response, model, timestamp 
+        SELECT id, query, response, model, citations, timestamp
         FROM chat_history 
         WHERE timestamp BETWEEN ? AND ?
         ORDER BY timestamp DESC
@@ -142,8 +218,8 @@ func (s *SQLiteStorage) GetHistoryByTimeRange(start, end time.Time) ([]map[strin
 	var history []map[string]interface{}
 	for rows.Next() {
 		var id int
-		var query, response, model, timestamp string
-		if err := rows.Scan(&id, &query, &response, &model, &timestamp); err != nil {
+		var query, response, model, citations, timestamp string
+		if err := rows.Scan(&id, &query, &response, &model, &citations, &timestamp); err != nil {
 			log.Printf("扫描行失败: %v", err)
 			return nil, fmt.Errorf("扫描行失败: %v", err)
 		}
@@ -153,6 +229,7 @@ func (s *SQLiteStorage) GetHistoryByTimeRange(start, end time.Time) ([]map[strin
 			"query":     query,
 			"response":  response,
 			"model":     model,
+			"citations": citations,
 			"timestamp": timestamp,
 		})
 	}
@@ -0,0 +1,78 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// buildCollectionPicker 构建选择当前对话检索哪些知识库集合的按钮
+func (mw *MainWindow) buildCollectionPicker() fyne.CanvasObject {
+	mw.collectionButton = widget.NewButtonWithIcon("", theme.StorageIcon(), mw.showCollectionPicker)
+	mw.updateCollectionButton()
+	return mw.collectionButton
+}
+
+// selectedCollections 返回当前对话检索的集合副本
+func (mw *MainWindow) selectedCollections() []string {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+	return append([]string(nil), mw.kbCollections...)
+}
+
+func (mw *MainWindow) setCollections(names []string) {
+	mw.conversationMu.Lock()
+	mw.kbCollections = names
+	mw.conversationMu.Unlock()
+	mw.updateCollectionButton()
+}
+
+func (mw *MainWindow) updateCollectionButton() {
+	names := mw.selectedCollections()
+	switch len(names) {
+	case 0:
+		mw.collectionButton.SetText("知识库: 不检索")
+	case 1, 2:
+		mw.collectionButton.SetText("知识库: " + strings.Join(names, ", "))
+	default:
+		mw.collectionButton.SetText(fmt.Sprintf("知识库: %s 等 %d 个", names[0], len(names)))
+	}
+}
+
+// showCollectionPicker 勾选当前对话检索的集合，全部取消表示不检索知识库
+func (mw *MainWindow) showCollectionPicker() {
+	names, err := mw.knowledgeBase.Collections()
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+
+	check := widget.NewCheckGroup(names, nil)
+	check.SetSelected(mw.selectedCollections())
+
+	d := dialog.NewCustomConfirm("检索的知识库", "确定", "取消", check, func(confirm bool) {
+		if confirm {
+			mw.setCollections(check.Selected)
+		}
+	}, mw.window)
+	d.Resize(fyne.NewSize(300, 300))
+	d.Show()
+}
+
+// renameSelectedCollection 集合被重命名（newName 为空表示删除）后同步当前对话的选择
+func (mw *MainWindow) renameSelectedCollection(oldName, newName string) {
+	names := mw.selectedCollections()
+	kept := names[:0]
+	for _, name := range names {
+		if name != oldName {
+			kept = append(kept, name)
+		} else if newName != "" {
+			kept = append(kept, newName)
+		}
+	}
+	mw.setCollections(kept)
+}
@@ -6,6 +6,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/fighthorse/aicode/go_aissistant/core/storage"
 	"os"
 	"strconv"
 	"time"
@@ -249,6 +250,18 @@ func (hw *HistoryWindow) exportEntry(id widget.ListItemID) {
 		return
 	}
 
+	citations, _ := entry["citations"].(string)
+	for _, c := range storage.ParseCitations(citations) {
+		source := c.Path
+		if c.Kind == storage.CitationWeb {
+			source = c.URL
+		}
+		if _, err := tempFile.WriteString(citationLabel(c) + " " + source + "\n"); err != nil {
+			dialog.ShowError(err, hw.window)
+			return
+		}
+	}
+
 	// 获取文件路径
 	filePath := tempFile.Name()
 
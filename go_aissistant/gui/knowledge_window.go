@@ -1,15 +1,19 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/fighthorse/aicode/go_aissistant/core/knowledgebase"
 	"path/filepath"
-	"time"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type KnowledgeWindow struct {
@@ -18,16 +22,21 @@ type KnowledgeWindow struct {
 	selectedId int
 	list       *widget.List
 	documents  []knowledgebase.Document
+
+	// 当前管理的集合
+	collection       string
+	collectionSelect *widget.Select
 }
 
 func NewKnowledgeWindow(mw *MainWindow) *KnowledgeWindow {
 	kw := &KnowledgeWindow{
 		mainWindow: mw,
 		window:     mw.app.NewWindow("知识库管理"),
+		collection: mw.knowledgeBase.DefaultCollection(),
 	}
 
 	kw.buildUI()
-	kw.refreshDocuments()
+	kw.refreshCollections()
 	kw.window.Resize(fyne.NewSize(600, 400))
 	return kw
 }
@@ -45,7 +54,7 @@ func (kw *KnowledgeWindow) buildUI() {
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
 			container := obj.(*fyne.Container)
 			label := container.Objects[1].(*widget.Label)
-			label.SetText(kw.documents[id].Metadata["source"].(string))
+			label.SetText(documentLabel(kw.documents[id]))
 		},
 	)
 	kw.list.OnSelected = func(id widget.ListItemID) {
@@ -54,13 +63,35 @@ func (kw *KnowledgeWindow) buildUI() {
 	// 工具栏
 	toolbar := widget.NewToolbar(
 		widget.NewToolbarAction(theme.FileIcon(), kw.onAddFile),
+		widget.NewToolbarAction(theme.FolderOpenIcon(), kw.onAddFolder),
 		widget.NewToolbarAction(theme.DeleteIcon(), kw.onDeleteDocument),
+		widget.NewToolbarAction(theme.HistoryIcon(), kw.onShowHistory),
 		widget.NewToolbarAction(theme.ViewRefreshIcon(), kw.refreshDocuments),
+		widget.NewToolbarAction(theme.MediaReplayIcon(), kw.onReEmbed),
+	)
+
+	// 集合选择
+	kw.collectionSelect = widget.NewSelect(nil, func(name string) {
+		if name != kw.collection {
+			kw.collection = name
+			kw.selectedId = -1
+			kw.list.UnselectAll()
+		}
+		kw.refreshDocuments()
+	})
+	collectionBar := container.NewBorder(nil, nil,
+		widget.NewLabel("集合:"),
+		container.NewHBox(
+			widget.NewButtonWithIcon("新建", theme.ContentAddIcon(), kw.onCreateCollection),
+			widget.NewButtonWithIcon("重命名", theme.DocumentCreateIcon(), kw.onRenameCollection),
+			widget.NewButtonWithIcon("删除", theme.DeleteIcon(), kw.onDropCollection),
+		),
+		kw.collectionSelect,
 	)
 
 	// 布局
 	content := container.NewBorder(
-		toolbar,
+		container.NewVBox(collectionBar, toolbar),
 		nil, nil, nil,
 		kw.list,
 	)
@@ -69,38 +100,178 @@ func (kw *KnowledgeWindow) buildUI() {
 }
 
 func (kw *KnowledgeWindow) onAddFile() {
-	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
-		if err != nil || reader == nil {
+	importer := kw.mainWindow.knowledgeBase.Importer()
+
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
 			dialog.ShowError(err, kw.window)
 			return
 		}
-		kw.importFile(reader.URI().Path())
+		if reader == nil {
+			return
+		}
+		reader.Close()
+		kw.importFile(importer, reader.URI().Path())
 	}, kw.window)
+
+	// 只列出支持导入的文件
+	var exts []string
+	for ext := range importer.Parser.SupportedFormats {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	open.SetFilter(storage.NewExtensionFileFilter(exts))
+	open.Show()
 }
 
-func (kw *KnowledgeWindow) importFile(path string) {
-	parser := knowledgebase.NewFileParser()
-	content, err := parser.ParseFile(path)
+func (kw *KnowledgeWindow) importFile(importer *knowledgebase.Importer, path string) {
+	kb, err := kw.currentKb()
 	if err != nil {
 		dialog.ShowError(err, kw.window)
 		return
 	}
-
-	doc := knowledgebase.Document{
-		ID:   generateDocID(),
-		Text: content,
-		Metadata: map[string]interface{}{
-			"source": filepath.Base(path),
-			"date":   time.Now().Format("2006-01-02"),
-		},
+	if _, err := importer.ImportFile(kb, path); err != nil {
+		dialog.ShowError(err, kw.window)
+		return
 	}
 
-	if err := kw.mainWindow.knowledgeBase.AddDocuments([]knowledgebase.Document{doc}); err != nil {
+	kw.refreshDocuments()
+}
+
+// onAddFolder 选择文件夹并设置过滤规则后递归导入
+func (kw *KnowledgeWindow) onAddFolder() {
+	dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, kw.window)
+			return
+		}
+		if uri == nil {
+			return
+		}
+
+		include := widget.NewEntry()
+		include.SetPlaceHolder("为空时导入全部支持的文件，如 *.md, docs/**/*.pdf")
+		exclude := widget.NewEntry()
+		exclude.SetText("node_modules, vendor, *.min.js")
+		tags := widget.NewEntry()
+		tags.SetPlaceHolder("可选，逗号分隔，检索时可按标签过滤")
+		dialog.ShowForm("导入文件夹", "导入", "取消", []*widget.FormItem{
+			widget.NewFormItem("文件夹", widget.NewLabel(uri.Path())),
+			widget.NewFormItem("包含", include),
+			widget.NewFormItem("排除", exclude),
+			widget.NewFormItem("标签", tags),
+		}, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			kw.importFolder(uri.Path(), knowledgebase.ParsePatterns(tags.Text), knowledgebase.FolderOptions{
+				Include: knowledgebase.ParsePatterns(include.Text),
+				Exclude: knowledgebase.ParsePatterns(exclude.Text),
+			})
+		}, kw.window)
+	}, kw.window)
+}
+
+// importFolder 在后台扫描并导入文件夹，显示逐个文件的进度和失败原因，可以中途取消
+func (kw *KnowledgeWindow) importFolder(root string, tags []string, opts knowledgebase.FolderOptions) {
+	kb, err := kw.currentKb()
+	if err != nil {
 		dialog.ShowError(err, kw.window)
 		return
 	}
+	importer := kw.mainWindow.knowledgeBase.Importer()
+	importer.Tags = tags
 
-	kw.refreshDocuments()
+	ctx, cancel := context.WithCancel(context.Background())
+	status := widget.NewLabel("正在扫描文件夹…")
+	bar := widget.NewProgressBar()
+	// failures 由导入协程追加，列表的回调在界面线程读取，都需持有 mu
+	var mu sync.Mutex
+	var failures []string
+	failureList := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(failures)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			mu.Lock()
+			text := failures[id]
+			mu.Unlock()
+			obj.(*widget.Label).SetText(text)
+		},
+	)
+	cancelButton := widget.NewButton("取消", func() {
+		cancel()
+		status.SetText(status.Text + "，正在取消…")
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(status, bar, widget.NewLabel("失败的文件:")),
+		cancelButton, nil, nil,
+		failureList,
+	)
+	progress := dialog.NewCustomWithoutButtons("导入文件夹", content, kw.window)
+	progress.Resize(fyne.NewSize(500, 360))
+	progress.Show()
+
+	go func() {
+		defer cancel()
+		files, err := importer.ScanFolder(root, opts)
+		switch {
+		case err != nil:
+			progress.Hide()
+			dialog.ShowError(err, kw.window)
+			return
+		case ctx.Err() != nil:
+			progress.Hide()
+			return
+		case len(files) == 0:
+			progress.Hide()
+			dialog.ShowInformation("提示", "文件夹中没有可导入的文件", kw.window)
+			return
+		}
+		status.SetText(fmt.Sprintf("0/%d", len(files)))
+		bar.Max = float64(len(files))
+		bar.Refresh()
+
+		done, chunks := 0, 0
+		err = importer.ImportFiles(ctx, kb, files, func(r knowledgebase.ImportResult) {
+			// 回调可能来自多个协程；刷新列表时会调用上面的回调，须先释放 mu
+			mu.Lock()
+			done++
+			if r.Err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", filepath.Base(r.Path), r.Err))
+			} else {
+				chunks += r.Chunks
+			}
+			text := fmt.Sprintf("%d/%d %s", done, len(files), filepath.Base(r.Path))
+			value := float64(done)
+			mu.Unlock()
+
+			if r.Err != nil {
+				failureList.Refresh()
+			}
+			status.SetText(text)
+			bar.SetValue(value)
+		})
+		progress.Hide()
+
+		mu.Lock()
+		failed := append([]string(nil), failures...)
+		msg := fmt.Sprintf("已导入 %d 个文件，共 %d 块，失败 %d 个", done-len(failed), chunks, len(failed))
+		mu.Unlock()
+		if err != nil {
+			msg = "导入已取消，" + msg
+		}
+		if n := len(failed); n > 0 {
+			// 失败较多时只列出前 10 个
+			msg += "\n\n" + strings.Join(failed[:min(n, 10)], "\n")
+		}
+		dialog.ShowInformation("导入完成", msg, kw.window)
+		kw.refreshDocuments()
+	}()
 }
 
 func (kw *KnowledgeWindow) onDeleteDocument() {
@@ -112,7 +283,12 @@ func (kw *KnowledgeWindow) onDeleteDocument() {
 
 	doc := kw.documents[selected]
 
-	if err := kw.mainWindow.knowledgeBase.DeleteDocument(doc.ID); err != nil {
+	kb, err := kw.currentKb()
+	if err != nil {
+		dialog.ShowError(err, kw.window)
+		return
+	}
+	if err := kb.DeleteDocument(doc.ID); err != nil {
 		dialog.ShowError(err, kw.window)
 		return
 	}
@@ -120,10 +296,132 @@ func (kw *KnowledgeWindow) onDeleteDocument() {
 	kw.refreshDocuments()
 }
 
+// onShowHistory 查看选中文档所属文件的导入版本，可以回滚到之前的版本
+func (kw *KnowledgeWindow) onShowHistory() {
+	if kw.selectedId < 0 || kw.selectedId >= len(kw.documents) {
+		dialog.ShowInformation("提示", "请选择一个文档查看版本历史", kw.window)
+		return
+	}
+	source, _ := kw.documents[kw.selectedId].Metadata[knowledgebase.MetaPath].(string)
+	if source == "" {
+		dialog.ShowInformation("提示", "该文档没有记录来源文件，无法查看版本历史", kw.window)
+		return
+	}
+
+	kb, err := kw.currentKb()
+	if err != nil {
+		dialog.ShowError(err, kw.window)
+		return
+	}
+	versions := kw.mainWindow.knowledgeBase.Versions()
+	history, err := versions.History(kb.Name(), source)
+	if err != nil {
+		dialog.ShowError(err, kw.window)
+		return
+	}
+	if len(history) == 0 {
+		dialog.ShowInformation("提示", "该文件没有版本记录，重新导入后开始记录", kw.window)
+		return
+	}
+
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(history) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(versionLabel(history[id], id == 0))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	var d dialog.Dialog
+	rollback := widget.NewButtonWithIcon("回滚到所选版本", theme.MediaSkipPreviousIcon(), func() {
+		if selected < 0 {
+			return
+		}
+		if selected == 0 {
+			dialog.ShowInformation("提示", "所选版本已是当前版本", kw.window)
+			return
+		}
+		v := history[selected]
+		msg := fmt.Sprintf("确定将 %s 回滚到版本 %d 吗？将用该版本的内容重新生成向量。", filepath.Base(source), v.Version)
+		if kw.mainWindow.isWatched(kb.Name(), source) {
+			msg += "\n该文件在监听的文件夹中，文件再次修改时会重新导入，回滚的内容将被覆盖。"
+		}
+		dialog.ShowConfirm("回滚", msg, func(ok bool) {
+			if !ok {
+				return
+			}
+			d.Hide()
+			progress := dialog.NewCustomWithoutButtons("回滚", widget.NewProgressBarInfinite(), kw.window)
+			progress.Show()
+			go func() {
+				err := versions.Rollback(kb, source, v.Version)
+				progress.Hide()
+				if err != nil {
+					dialog.ShowError(err, kw.window)
+					return
+				}
+				kw.refreshDocuments()
+			}()
+		}, kw.window)
+	})
+
+	d = dialog.NewCustom("版本历史："+filepath.Base(source), "关闭",
+		container.NewBorder(nil, rollback, nil, nil, list), kw.window)
+	d.Resize(fyne.NewSize(520, 360))
+	d.Show()
+}
+
+// versionLabel 版本列表中显示版本号、导入时间、块数和内容哈希前缀
+func versionLabel(v knowledgebase.SourceVersion, current bool) string {
+	label := fmt.Sprintf("版本 %d  %s  %d 块  %.8s", v.Version, v.CreatedAt.Local().Format("2006-01-02 15:04"), v.Chunks, v.Hash)
+	if v.Note != "" {
+		label += "  " + v.Note
+	}
+	if current {
+		label += "（当前）"
+	}
+	return label
+}
+
+// onReEmbed 更换向量模型后重建全部向量
+func (kw *KnowledgeWindow) onReEmbed() {
+	msg := fmt.Sprintf("将使用向量模型 %s 重新生成集合 %s 中全部文档的向量，耗时可能较长，是否继续？",
+		kw.mainWindow.config.EmbeddingModel, kw.collection)
+	dialog.ShowConfirm("重新向量化", msg, func(b bool) {
+		if !b {
+			return
+		}
+		progress := dialog.NewCustomWithoutButtons("重新向量化", widget.NewProgressBarInfinite(), kw.window)
+		progress.Show()
+		go func() {
+			kb, err := kw.currentKb()
+			if err == nil {
+				err = kb.ReEmbed()
+			}
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(err, kw.window)
+				return
+			}
+			dialog.ShowInformation("完成", "重新向量化完成", kw.window)
+			kw.refreshDocuments()
+		}()
+	}, kw.window)
+}
+
 func (kw *KnowledgeWindow) refreshDocuments() {
+	kb, err := kw.currentKb()
+	if err != nil {
+		dialog.ShowError(err, kw.window)
+		return
+	}
+
 	// 获取所有文档
-	var err error
-	kw.documents, err = kw.mainWindow.knowledgeBase.ListDocuments()
+	kw.documents, err = kb.ListDocuments()
 	if err != nil {
 		dialog.ShowError(err, kw.window)
 		return
@@ -132,7 +430,99 @@ func (kw *KnowledgeWindow) refreshDocuments() {
 	kw.list.Refresh()
 }
 
-func generateDocID() string {
-	// 简单的文档ID生成逻辑，可以根据需要改进
-	return fmt.Sprintf("doc-%d", time.Now().UnixNano())
+func (kw *KnowledgeWindow) currentKb() (knowledgebase.KnowledgeBaseI, error) {
+	return kw.mainWindow.knowledgeBase.Collection(kw.collection)
+}
+
+// refreshCollections 重新加载集合列表并选中当前集合，随后刷新文档列表
+func (kw *KnowledgeWindow) refreshCollections() {
+	names, err := kw.mainWindow.knowledgeBase.Collections()
+	if err != nil {
+		dialog.ShowError(err, kw.window)
+		return
+	}
+	kw.collectionSelect.Options = names
+	kw.collectionSelect.SetSelected(kw.collection)
+}
+
+func (kw *KnowledgeWindow) onCreateCollection() {
+	name := widget.NewEntry()
+	name.SetPlaceHolder("字母开头，字母、数字或下划线")
+	dialog.ShowForm("新建集合", "创建", "取消", []*widget.FormItem{
+		widget.NewFormItem("名称", name),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		newName := strings.TrimSpace(name.Text)
+		if err := kw.mainWindow.knowledgeBase.CreateCollection(newName); err != nil {
+			dialog.ShowError(err, kw.window)
+			return
+		}
+		kw.collection = newName
+		kw.refreshCollections()
+	}, kw.window)
+}
+
+func (kw *KnowledgeWindow) onRenameCollection() {
+	oldName := kw.collection
+	if kw.mainWindow.isWatchedCollection(oldName) {
+		dialog.ShowInformation("提示", fmt.Sprintf("集合 %s 正由监听文件夹使用，请先在配置的 watch_folders 中修改", oldName), kw.window)
+		return
+	}
+	name := widget.NewEntry()
+	name.SetText(oldName)
+	dialog.ShowForm("重命名集合", "确定", "取消", []*widget.FormItem{
+		widget.NewFormItem("新名称", name),
+	}, func(confirm bool) {
+		newName := strings.TrimSpace(name.Text)
+		if !confirm || newName == oldName {
+			return
+		}
+		if err := kw.mainWindow.knowledgeBase.RenameCollection(oldName, newName); err != nil {
+			dialog.ShowError(err, kw.window)
+			return
+		}
+		kw.mainWindow.renameSelectedCollection(oldName, newName)
+		kw.collection = newName
+		kw.refreshCollections()
+	}, kw.window)
+}
+
+func (kw *KnowledgeWindow) onDropCollection() {
+	name := kw.collection
+	if kw.mainWindow.isWatchedCollection(name) {
+		dialog.ShowInformation("提示", fmt.Sprintf("集合 %s 正由监听文件夹使用，请先在配置的 watch_folders 中移除", name), kw.window)
+		return
+	}
+	dialog.ShowConfirm("确认", fmt.Sprintf("确定要删除集合 %s 及其中的全部文档吗？", name), func(b bool) {
+		if !b {
+			return
+		}
+		if err := kw.mainWindow.knowledgeBase.DropCollection(name); err != nil {
+			dialog.ShowError(err, kw.window)
+			return
+		}
+		kw.mainWindow.renameSelectedCollection(name, "")
+		kw.collection = kw.mainWindow.knowledgeBase.DefaultCollection()
+		kw.refreshCollections()
+	}, kw.window)
+}
+
+// documentLabel 列表中显示来源文件、页码和块序号
+func documentLabel(doc knowledgebase.Document) string {
+	source, _ := doc.Metadata["source"].(string)
+	if source == "" {
+		source = doc.ID
+	}
+	if symbol, _ := doc.Metadata[knowledgebase.MetaSymbol].(string); symbol != "" {
+		source += " " + symbol
+	}
+	if page, ok := doc.Metadata[knowledgebase.MetaPage]; ok {
+		source += fmt.Sprintf(" 第%v页", page)
+	}
+	if index, ok := doc.Metadata[knowledgebase.MetaChunkIndex]; ok {
+		return fmt.Sprintf("%s [%v]", source, index)
+	}
+	return source
 }
@@ -15,40 +15,83 @@ import (
 	"github.com/fighthorse/aicode/go_aissistant/core/storage"
 	"github.com/fighthorse/aicode/go_aissistant/core/websearch"
 	"log"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// 每次请求携带的最大历史消息条数（不含系统提示）
+	maxConversationMessages = 20
+	// 未设置 num_predict 时为回答预留的 token 数
+	defaultAnswerTokens = 512
+	// 未设置 num_ctx 时按模型的上下文长度使用的上限，避免长上下文模型占用过多显存
+	maxAutoNumCtx = 8192
+	systemPrompt  = "你是一个乐于助人的AI助手，请结合对话上下文和提供的参考信息，准确地回答用户的问题。"
+)
+
 type MainWindow struct {
 	app    fyne.App
 	window fyne.Window
 	config *config.AppConfig
 
 	// 核心组件
-	aiClient      *ai_model.OllamaClient
-	knowledgeBase knowledgebase.KnowledgeBaseI
+	aiClient      ai_model.Provider
+	reranker      knowledgebase.Reranker       // 未启用重排时为 nil
+	expander      *knowledgebase.QueryExpander // 未开启问题改写和扩展时为 nil
+	ollamaClient  *ai_model.OllamaClient       // 模型管理只支持 Ollama
+	knowledgeBase *knowledgebase.KnowledgeBaseManager
 	storage       *storage.SQLiteStorage
 	searchClient  websearch.WebSearchI
 
+	// 当前对话的历史消息、检索的知识库集合和过滤条件
+	conversation   []ai_model.Message
+	kbCollections  []string
+	kbFilter       knowledgebase.QueryOptions
+	conversationMu sync.Mutex
+
+	// 当前使用的生成参数
+	genOptions *ai_model.Options
+	optionsMu  sync.Mutex
+
+	// 正在进行的请求，用于停止生成
+	cancelQuery context.CancelFunc
+	queryMu     sync.Mutex
+
+	// 监听文件夹的停止函数，关闭窗口时全部停止
+	stopWatchers   []context.CancelFunc
+	watchersClosed bool
+	watchersMu     sync.Mutex
+
 	// UI组件
-	inputEntry  *widget.Entry
-	outputText  *widget.Label
-	statusLabel *widget.Label
-	modelSelect *widget.Select
-	historyList *widget.List
-	progressBar *widget.ProgressBarInfinite
+	inputEntry       *widget.Entry
+	outputText       *StreamingLabel
+	outputScroll     *container.Scroll
+	statusLabel      *widget.Label
+	modelSelect      *widget.Select
+	presetSelect     *widget.Select
+	collectionButton *widget.Button
+	filterChips      *fyne.Container
+	sourcesPanel     *fyne.Container
+	sourcesList      *fyne.Container
+	historyList      *widget.List
+	progressBar      *widget.ProgressBarInfinite
+	sendButton       *widget.Button
+	stopButton       *widget.Button
 }
 
-func NewMainWindow(app fyne.App, config *config.AppConfig, kknowledgeBase knowledgebase.KnowledgeBaseI) *MainWindow {
+func NewMainWindow(app fyne.App, config *config.AppConfig, kknowledgeBase *knowledgebase.KnowledgeBaseManager) *MainWindow {
 	mw := &MainWindow{
 		app:           app,
 		config:        config,
 		window:        app.NewWindow("GoAIssistant"),
 		inputEntry:    widget.NewEntry(),
-		outputText:    widget.NewLabel(""),
+		outputText:    NewStreamingLabel(),
 		statusLabel:   widget.NewLabel("就绪"),
 		progressBar:   widget.NewProgressBarInfinite(),
 		knowledgeBase: kknowledgeBase,
+		kbCollections: []string{kknowledgeBase.DefaultCollection()},
 	}
 
 	mw.outputText.TextStyle = fyne.TextStyle{
@@ -60,9 +103,11 @@ func NewMainWindow(app fyne.App, config *config.AppConfig, kknowledgeBase knowle
 	mw.buildUI()
 	//mw.setupShortcuts()
 	mw.window.Resize(fyne.NewSize(800, 600))
+	mw.window.SetOnClosed(mw.stopFolderWatchers)
 
 	// 加载初始数据
 	go mw.loadInitialData()
+	go mw.startFolderWatchers()
 
 	return mw
 }
@@ -70,8 +115,25 @@ func NewMainWindow(app fyne.App, config *config.AppConfig, kknowledgeBase knowle
 func (mw *MainWindow) initializeComponents() {
 	var err error
 
-	// 初始化AI客户端
-	mw.aiClient = ai_model.NewOllamaClient(mw.config.OllamaURL)
+	// 初始化AI客户端，按模型分发到配置的提供方
+	router, err := ai_model.NewRouter(mw.config)
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+		mw.aiClient = ai_model.NewOllamaClient(mw.config.OllamaURL)
+	} else {
+		mw.aiClient = router
+		if p, ok := router.Provider(config.ProviderOllama); ok {
+			mw.ollamaClient, _ = p.(*ai_model.OllamaClient)
+		}
+	}
+	if mw.ollamaClient == nil {
+		mw.ollamaClient = ai_model.NewOllamaClient(mw.config.OllamaURL)
+	}
+	mw.reranker, err = knowledgebase.NewRerankerFromConfig(mw.config, mw.aiClient)
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+	}
+	mw.expander = knowledgebase.NewQueryExpanderFromConfig(mw.config, mw.aiClient)
 
 	// 初始化存储
 	mw.storage, err = storage.NewSQLiteStorage(mw.config.SQLitePath)
@@ -126,11 +188,13 @@ func (mw *MainWindow) buildUI() {
 		mw.historyList,
 	)
 
-	// 使用 widget.NewLabelWrap 创建自动换行的标签
-	// 修改输出区域的创建方式
-	mw.outputText = widget.NewLabel("")
-	// 修改后
-	mw.outputText.Wrapping = fyne.TextWrapWord // 启用自动换行
+	// 输出区域随流式内容自动滚动到底部
+	mw.outputScroll = container.NewVScroll(mw.outputText)
+	mw.outputText.OnChanged = mw.outputScroll.ScrollToBottom
+
+	mw.sendButton = widget.NewButtonWithIcon("发送", theme.MailSendIcon(), mw.onSend)
+	mw.stopButton = widget.NewButtonWithIcon("停止", theme.MediaStopIcon(), mw.onStop)
+	mw.stopButton.Hide()
 
 	rightPanel := container.NewBorder(
 		container.NewVBox(
@@ -138,18 +202,23 @@ func (mw *MainWindow) buildUI() {
 			container.NewHBox(
 				widget.NewLabel("选择模型:"),
 				mw.modelSelect,
+				mw.buildOptionsPanel(),
+				mw.buildCollectionPicker(),
 				layout.NewSpacer(),
 				mw.statusLabel,
 			),
+			mw.buildFilterBar(),
 			mw.inputEntry,
 			container.NewHBox(
-				widget.NewButtonWithIcon("发送", theme.MailSendIcon(), mw.onSend),
+				mw.sendButton,
+				mw.stopButton,
+				widget.NewButtonWithIcon("新对话", theme.ContentAddIcon(), mw.newConversation),
 				mw.progressBar, // 确保 progressBar 在这里
 			),
 		),
 		nil, nil, nil,
-		container.NewVScroll(mw.outputText), // 使用垂直滚动容器
-		//outputScroll, // 使用垂直滚动容器
+		// 使用垂直滚动容器，下方为本轮回答的参考来源
+		container.NewBorder(nil, mw.buildSourcesPanel(), nil, nil, mw.outputScroll),
 	)
 
 	split := container.NewHSplit(leftPanel, rightPanel)
@@ -165,6 +234,7 @@ func (mw *MainWindow) buildToolbar() *widget.Toolbar {
 	return widget.NewToolbar(
 		widget.NewToolbarAction(theme.FolderOpenIcon(), mw.onImportFile),
 		widget.NewToolbarAction(theme.StorageIcon(), mw.showKnowledgeManager),
+		widget.NewToolbarAction(theme.ComputerIcon(), mw.showModelManager),
 		widget.NewToolbarAction(theme.HistoryIcon(), mw.showFullHistory),
 		widget.NewToolbarAction(theme.SettingsIcon(), mw.showSettings),
 	)
@@ -176,74 +246,247 @@ func (mw *MainWindow) onSend() {
 		return
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	if !mw.beginQuery(cancel) {
+		cancel()
+		return
+	}
+
+	mw.outputText.Reset()
+	mw.showSources(nil, "")
 	mw.progressBar.Show()
 	mw.statusLabel.SetText("处理中...")
 	mw.progressBar.Refresh()
 	mw.statusLabel.Refresh()
 
 	go func() {
+		defer mw.endQuery()
+
 		// 执行查询流程
-		response, err := mw.processQuery(question, mw.modelSelect.Selected)
+		_, err := mw.processQuery(ctx, question, mw.modelSelect.Selected)
 		if err != nil {
+			mw.statusLabel.SetText("出错")
 			dialog.ShowError(err, mw.window)
 			return
 		}
 
 		// 更新UI
 		mw.app.SendNotification(fyne.NewNotification("收到回复", "点击查看"))
-		mw.outputText.SetText(response)
 		mw.inputEntry.SetText("")
 		mw.inputEntry.Refresh()
 
 		// 刷新历史记录
 		mw.refreshHistory()
-
-		// loading end
-		mw.progressBar.Hide()
-		mw.statusLabel.SetText("就绪")
-		mw.progressBar.Refresh()
-		mw.statusLabel.Refresh()
 	}()
 }
 
-func (mw *MainWindow) processQuery(question string, model string) (string, error) {
-	// 步骤1：查询知识库
-	kbResults, _ := mw.knowledgeBase.Query(question, 3)
+// onStop 取消正在进行的生成
+func (mw *MainWindow) onStop() {
+	mw.queryMu.Lock()
+	defer mw.queryMu.Unlock()
+	if mw.cancelQuery != nil {
+		mw.cancelQuery()
+		mw.statusLabel.SetText("正在停止...")
+	}
+}
+
+// beginQuery 登记新的请求，已有请求进行中时返回 false
+func (mw *MainWindow) beginQuery(cancel context.CancelFunc) bool {
+	mw.queryMu.Lock()
+	defer mw.queryMu.Unlock()
+	if mw.cancelQuery != nil {
+		return false
+	}
+	mw.cancelQuery = cancel
+
+	mw.sendButton.Disable()
+	mw.stopButton.Show()
+	return true
+}
 
-	var kbStr []string
-	for _, doc := range kbResults {
-		kbStr = append(kbStr, doc.Text)
+func (mw *MainWindow) endQuery() {
+	mw.queryMu.Lock()
+	if mw.cancelQuery != nil {
+		mw.cancelQuery()
+		mw.cancelQuery = nil
+	}
+	mw.queryMu.Unlock()
+
+	// loading end
+	mw.sendButton.Enable()
+	mw.stopButton.Hide()
+	mw.progressBar.Hide()
+	mw.progressBar.Refresh()
+}
+
+func (mw *MainWindow) processQuery(ctx context.Context, question string, model string) (string, error) {
+	if model == "" {
+		model = mw.config.DefaultModel
+	}
+
+	// 步骤1：在当前对话选择的集合中按过滤条件查询知识库，未选择集合时不检索。
+	// 开启问题改写和扩展时先得到若干检索问题，分别检索后合并
+	var kbResults []knowledgebase.Document
+	queries := []string{question}
+	if opts := mw.queryOptions(); len(opts.Collections) > 0 {
+		if mw.expander != nil {
+			mw.statusLabel.SetText("正在改写问题...")
+			expander := *mw.expander
+			if expander.Model == "" {
+				expander.Model = model
+			}
+			queries = expander.Expand(ctx, question, mw.conversationHistory())
+		}
+		kbResults, _ = mw.knowledgeBase.QueryMulti(queries, opts)
+		if mw.reranker != nil && len(kbResults) > 0 {
+			mw.statusLabel.SetText(fmt.Sprintf("正在重排 %d 条候选...", len(kbResults)))
+		}
+		kbResults = knowledgebase.RerankDocuments(ctx, mw.reranker, queries[0], kbResults, len(kbResults))
+		kbResults = knowledgebase.Diversify(kbResults, mw.config.KBTopK, mw.config.MMRLambda)
 	}
 	fmt.Println("knowledgeBase Query")
 	// 步骤2：执行网络搜索
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	searchCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
-	webResults, _ := mw.searchClient.Search(ctx, question, 5)
+	webResults, _ := mw.searchClient.Search(searchCtx, question, 5)
 
 	fmt.Println("searchClient Search")
-	// 步骤3：构建提示
+	// 步骤3：按上下文长度打包知识库内容，合并同一来源的相邻块，然后构建带编号来源的提示
+	genOptions := mw.currentOptions()
+	kbResults = knowledgebase.PackContext(kbResults, mw.contextBudget(genOptions, model, buildPrompt(question, nil, webResults)))
+	citations := buildCitations(kbResults, webResults)
 	prompt := question
-	if len(kbStr) > 0 && len(webResults) > 0 {
-		prompt = buildPrompt(question, kbStr, webResults)
+	if len(citations) > 0 {
+		prompt = buildPrompt(question, kbResults, webResults)
 	}
 
-	// 步骤4：调用AI生成
-	if model == "" {
-		model = mw.config.DefaultModel
-	}
-	fmt.Println("aiClient Generate", prompt, " ", model)
-	response, err := mw.aiClient.Generate(prompt, model)
+	// 步骤4：携带历史对话流式调用AI生成
+	messages := mw.buildMessages(prompt)
+	log.Printf("调用模型 %s，消息数：%d", model, len(messages))
+	stream, err := mw.aiClient.ChatStream(ctx, model, messages, genOptions)
 	if err != nil {
-		return err.Error(), nil
+		return "", err
 	}
 
-	// 步骤5：保存记录
-	_ = mw.storage.SaveChatRecord(question, response, mw.config.DefaultModel)
+	var builder strings.Builder
+	var stats *ai_model.GenerationStats
+	for chunk := range stream {
+		switch chunk.Type {
+		case ai_model.ChunkText:
+			builder.WriteString(chunk.Text)
+			mw.outputText.Append(chunk.Text)
+		case ai_model.ChunkDone:
+			stats = chunk.Stats
+		case ai_model.ChunkError:
+			err = chunk.Err
+		}
+	}
+
+	response := builder.String()
+	stopped := ctx.Err() != nil
+	if err != nil && response == "" {
+		return "", err
+	}
+	if stopped {
+		if response == "" {
+			mw.statusLabel.SetText("已停止")
+			return "", nil
+		}
+		response += "\n\n[已停止生成]"
+		mw.outputText.Append("\n\n[已停止生成]")
+	}
+	if queries[0] != question {
+		mw.outputText.Append("\n\n检索问题：" + queries[0])
+	}
+	mw.showSources(citations, response)
+	mw.appendConversation(question, response)
+
+	// 步骤5：保存记录（用户中途停止时保存已生成的部分）
+	_ = mw.storage.SaveChatRecord(question, response, model, citations)
 	log.Println("模型：", model, " 构建提示：", prompt, " 返回：", len(response))
+
+	switch {
+	case err != nil:
+		mw.statusLabel.SetText("生成中断：" + err.Error())
+	case stopped:
+		mw.statusLabel.SetText("已停止")
+	case stats != nil:
+		mw.statusLabel.SetText(fmt.Sprintf("就绪（%d tokens，%.1f tokens/s）", stats.EvalCount, stats.TokensPerSecond()))
+	default:
+		mw.statusLabel.SetText("就绪")
+	}
 	return response, nil
 }
 
+// buildMessages 组装系统提示、当前对话历史以及本轮问题
+func (mw *MainWindow) buildMessages(prompt string) []ai_model.Message {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+
+	messages := make([]ai_model.Message, 0, len(mw.conversation)+2)
+	messages = append(messages, ai_model.Message{Role: ai_model.RoleSystem, Content: systemPrompt})
+	messages = append(messages, mw.conversation...)
+	messages = append(messages, ai_model.Message{Role: ai_model.RoleUser, Content: prompt})
+	return messages
+}
+
+// contextBudget 知识库内容可用的 token 数，由 num_ctx 扣除回答长度、系统提示、对话历史和提示词的其余部分得到。
+// 未设置 num_ctx 时使用模型的上下文长度（不超过 maxAutoNumCtx）并写入 opts 随请求发送，
+// 使 Ollama 实际使用的上下文与这里的计算一致；无法获取模型的上下文长度时按 Ollama 默认的 2048 计算
+func (mw *MainWindow) contextBudget(opts *ai_model.Options, model, prompt string) int {
+	numCtx := ai_model.IntValue(opts.NumCtx, 0)
+	if numCtx <= 0 {
+		numCtx = min(ai_model.ContextLength(mw.aiClient, model), maxAutoNumCtx)
+		if numCtx > 0 {
+			opts.NumCtx = ai_model.Int(numCtx)
+		} else {
+			numCtx = ai_model.DefaultNumCtx
+		}
+	}
+	reserve := ai_model.IntValue(opts.NumPredict, 0)
+	if reserve <= 0 {
+		reserve = defaultAnswerTokens
+	}
+	texts := []string{systemPrompt, prompt}
+	for _, m := range mw.conversationHistory() {
+		texts = append(texts, m.Content)
+	}
+	return knowledgebase.ContextBudget(numCtx, reserve, texts...)
+}
+
+// conversationHistory 当前对话历史的副本
+func (mw *MainWindow) conversationHistory() []ai_model.Message {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+	return append([]ai_model.Message(nil), mw.conversation...)
+}
+
+// appendConversation 记录一轮问答，历史中只保留原始问题以节省上下文
+func (mw *MainWindow) appendConversation(question, response string) {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+
+	mw.conversation = append(mw.conversation,
+		ai_model.Message{Role: ai_model.RoleUser, Content: question},
+		ai_model.Message{Role: ai_model.RoleAssistant, Content: response},
+	)
+	if len(mw.conversation) > maxConversationMessages {
+		mw.conversation = mw.conversation[len(mw.conversation)-maxConversationMessages:]
+	}
+}
+
+// newConversation 清空当前对话，后续提问不再携带之前的上下文
+func (mw *MainWindow) newConversation() {
+	mw.conversationMu.Lock()
+	mw.conversation = nil
+	mw.conversationMu.Unlock()
+
+	mw.outputText.SetText("")
+	mw.showSources(nil, "")
+	mw.statusLabel.SetText("新对话")
+}
+
 func (mw *MainWindow) refreshModelList() {
 	models, err := mw.aiClient.ListLocalModels()
 	if err != nil {
@@ -282,12 +525,105 @@ func (mw *MainWindow) loadInitialData() {
 	}
 }
 
+// startFolderWatchers 在后台同步配置中的监听文件夹，同步结果显示在状态栏
+func (mw *MainWindow) startFolderWatchers() {
+	for _, wf := range mw.config.WatchFolders {
+		collection := mw.watchCollection(wf)
+		kb, err := mw.knowledgeBase.EnsureCollection(collection)
+		if err != nil {
+			log.Printf("监听文件夹 %s 失败: %v", wf.Path, err)
+			continue
+		}
+		importer := mw.knowledgeBase.Importer()
+		importer.Tags = wf.Tags
+		watcher, err := knowledgebase.NewFolderWatcher(importer, kb, wf.Path, knowledgebase.FolderOptions{
+			Include: wf.Include,
+			Exclude: wf.Exclude,
+		})
+		if err != nil {
+			log.Printf("监听文件夹 %s 失败: %v", wf.Path, err)
+			continue
+		}
+		watcher.OnSync = func(ev knowledgebase.SyncEvent) {
+			if ev.Err != nil {
+				mw.statusLabel.SetText(fmt.Sprintf("同步 %s 失败: %v", filepath.Base(ev.Path), ev.Err))
+				return
+			}
+			mw.statusLabel.SetText(fmt.Sprintf("知识库 %s 已同步：%s %s", collection, ev.Action, filepath.Base(ev.Path)))
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		mw.watchersMu.Lock()
+		if mw.watchersClosed {
+			mw.watchersMu.Unlock()
+			cancel()
+			return
+		}
+		mw.stopWatchers = append(mw.stopWatchers, cancel)
+		mw.watchersMu.Unlock()
+		go func() {
+			if err := watcher.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("监听文件夹 %s 已停止: %v", watcher.Root(), err)
+			}
+		}()
+	}
+}
+
+// stopFolderWatchers 停止全部监听文件夹，之后不再启动新的监听
+func (mw *MainWindow) stopFolderWatchers() {
+	mw.watchersMu.Lock()
+	defer mw.watchersMu.Unlock()
+	mw.watchersClosed = true
+	for _, cancel := range mw.stopWatchers {
+		cancel()
+	}
+	mw.stopWatchers = nil
+}
+
+// watchCollection 监听文件夹导入的集合
+func (mw *MainWindow) watchCollection(wf config.WatchFolderConfig) string {
+	if wf.Collection == "" {
+		return mw.knowledgeBase.DefaultCollection()
+	}
+	return wf.Collection
+}
+
+// isWatchedCollection 集合是否由配置中的监听文件夹使用
+func (mw *MainWindow) isWatchedCollection(collection string) bool {
+	for _, wf := range mw.config.WatchFolders {
+		if mw.watchCollection(wf) == collection {
+			return true
+		}
+	}
+	return false
+}
+
+// isWatched 文件是否位于监听该集合的文件夹中
+func (mw *MainWindow) isWatched(collection, path string) bool {
+	for _, wf := range mw.config.WatchFolders {
+		root, err := filepath.Abs(wf.Path)
+		if err != nil || mw.watchCollection(wf) != collection {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (mw *MainWindow) showHistoryDetail(entry map[string]string) {
-	dialog.ShowCustom("历史详情", "关闭", container.NewVBox(
+	content := container.NewVBox(
 		widget.NewLabel(fmt.Sprintf("问题：%s", entry["query"])),
 		widget.NewLabel(fmt.Sprintf("回答：%s", entry["response"])),
 		widget.NewLabel(fmt.Sprintf("模型：%s", entry["model"])),
-	), mw.window)
+	)
+	if citations := storage.ParseCitations(entry["citations"]); len(citations) > 0 {
+		content.Add(widget.NewLabel("参考来源："))
+		for _, obj := range mw.citationButtons(citations, entry["response"]) {
+			content.Add(obj)
+		}
+	}
+	dialog.ShowCustom("历史详情", "关闭", content, mw.window)
 	mw.refreshHistory()
 }
 
@@ -313,6 +649,11 @@ func (mw *MainWindow) showKnowledgeManager() {
 	kw.window.Show()
 }
 
+func (mw *MainWindow) showModelManager() {
+	w := NewModelWindow(mw)
+	w.window.Show()
+}
+
 func (mw *MainWindow) showFullHistory() {
 	hw := NewHistoryWindow(mw)
 	hw.window.Show()
@@ -335,23 +676,38 @@ func (mw *MainWindow) Show() {
 	}()
 }
 
-func buildPrompt(question string, kbResults []string, webResults []websearch.SearchResult) string {
+// buildPrompt 为参考内容统一编号（知识库在前、网络搜索结果在后，与 buildCitations 一致），并要求模型用 [n] 标注引用
+func buildPrompt(question string, kbResults []knowledgebase.Document, webResults []websearch.SearchResult) string {
 	var builder strings.Builder
+	n := 0
 
 	// 知识库内容
-	builder.WriteString("知识库参考内容：\n")
-	for i, doc := range kbResults {
-		builder.WriteString(fmt.Sprintf("[知识%d] %s\n", i+1, doc))
+	if len(kbResults) > 0 {
+		builder.WriteString("知识库参考内容：\n")
+		for _, doc := range kbResults {
+			n++
+			source, _ := doc.Metadata["source"].(string)
+			if page := doc.Page(); page > 0 {
+				source += fmt.Sprintf(" 第%d页", page)
+			}
+			builder.WriteString(fmt.Sprintf("[%d] 来源：%s\n%s\n", n, source, doc.Text))
+		}
+		builder.WriteString("\n")
 	}
 
 	// 网络搜索结果
-	builder.WriteString("\n网络搜索结果：\n")
-	for i, result := range webResults {
-		builder.WriteString(fmt.Sprintf("[网络%d] %s\n%s\n", i+1, result.Title, result.Snippet))
+	if len(webResults) > 0 {
+		builder.WriteString("网络搜索结果：\n")
+		for _, result := range webResults {
+			n++
+			builder.WriteString(fmt.Sprintf("[%d] %s（%s）\n%s\n", n, result.Title, result.Link, result.Snippet))
+		}
+		builder.WriteString("\n")
 	}
 
 	// 最终问题
-	builder.WriteString(fmt.Sprintf("\n请根据以上信息回答：%s", question))
+	builder.WriteString(fmt.Sprintf("请根据以上信息回答：%s\n", question))
+	builder.WriteString("使用参考内容时，在相应句子末尾用方括号标注来源编号，如 [1] 或 [1][3]；不要标注不存在的编号，参考内容中没有的信息不要标注。")
 
 	return builder.String()
 }
@@ -0,0 +1,238 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+)
+
+type ModelWindow struct {
+	window     fyne.Window
+	mainWindow *MainWindow
+	client     *ai_model.OllamaClient
+
+	list       *widget.List
+	models     []string
+	selectedId int
+	pullEntry  *widget.Entry
+	downloads  *fyne.Container
+}
+
+func NewModelWindow(mw *MainWindow) *ModelWindow {
+	w := &ModelWindow{
+		mainWindow: mw,
+		client:     mw.ollamaClient,
+		window:     mw.app.NewWindow("模型管理"),
+		selectedId: -1,
+	}
+
+	w.buildUI()
+	go w.refreshModels()
+	w.window.Resize(fyne.NewSize(600, 500))
+	return w
+}
+
+func (w *ModelWindow) buildUI() {
+	// 本地模型列表
+	w.list = widget.NewList(
+		func() int { return len(w.models) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewIcon(theme.ComputerIcon()),
+				widget.NewLabel(""),
+			)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			container := obj.(*fyne.Container)
+			label := container.Objects[1].(*widget.Label)
+			label.SetText(w.models[id])
+		},
+	)
+	w.list.OnSelected = func(id widget.ListItemID) {
+		w.selectedId = id
+	}
+
+	// 工具栏
+	toolbar := widget.NewToolbar(
+		widget.NewToolbarAction(theme.InfoIcon(), w.onShowModel),
+		widget.NewToolbarAction(theme.ContentCopyIcon(), w.onCopyModel),
+		widget.NewToolbarAction(theme.DeleteIcon(), w.onDeleteModel),
+		widget.NewToolbarAction(theme.ViewRefreshIcon(), func() { go w.refreshModels() }),
+	)
+
+	// 下载区域
+	w.pullEntry = widget.NewEntry()
+	w.pullEntry.SetPlaceHolder("模型名称，如 qwen2.5:7b")
+	w.pullEntry.OnSubmitted = func(string) { w.onPullModel() }
+	pullBar := container.NewBorder(nil, nil, widget.NewLabel("下载模型:"),
+		widget.NewButtonWithIcon("下载", theme.DownloadIcon(), w.onPullModel),
+		w.pullEntry,
+	)
+	w.downloads = container.NewVBox()
+
+	w.window.SetContent(container.NewBorder(
+		container.NewVBox(toolbar, pullBar, w.downloads),
+		nil, nil, nil,
+		w.list,
+	))
+}
+
+func (w *ModelWindow) refreshModels() {
+	models, err := w.client.ListLocalModels()
+	if err != nil {
+		dialog.ShowError(err, w.window)
+		return
+	}
+	w.models = models
+	w.selectedId = -1
+	w.list.UnselectAll()
+	w.list.Refresh()
+}
+
+func (w *ModelWindow) selectedModel() (string, bool) {
+	if w.selectedId < 0 || w.selectedId >= len(w.models) {
+		dialog.ShowInformation("提示", "请先选择一个模型", w.window)
+		return "", false
+	}
+	return w.models[w.selectedId], true
+}
+
+// onPullModel 开始下载模型，每个下载任务一行进度条，可单独取消
+func (w *ModelWindow) onPullModel() {
+	name := strings.TrimSpace(w.pullEntry.Text)
+	if name == "" {
+		return
+	}
+	w.pullEntry.SetText("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	status := widget.NewLabel("准备下载...")
+	bar := widget.NewProgressBar()
+	cancelBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), cancel)
+	row := container.NewBorder(nil, nil,
+		widget.NewLabel(name), cancelBtn,
+		container.NewVBox(bar, status),
+	)
+	w.downloads.Add(row)
+
+	go func() {
+		defer cancel()
+		err := w.client.PullModel(ctx, name, func(p ai_model.PullProgress) {
+			if f := p.Fraction(); f >= 0 {
+				bar.SetValue(f)
+				status.SetText(fmt.Sprintf("%s  %s / %s", p.Status, formatBytes(p.Completed), formatBytes(p.Total)))
+			} else {
+				status.SetText(p.Status)
+			}
+		})
+
+		cancelBtn.Disable()
+		switch {
+		case ctx.Err() != nil:
+			status.SetText("已取消")
+		case err != nil:
+			status.SetText("下载失败")
+			dialog.ShowError(err, w.window)
+		default:
+			bar.SetValue(1)
+			status.SetText("下载完成")
+			w.refreshModels()
+			w.mainWindow.refreshModelList()
+		}
+	}()
+}
+
+func (w *ModelWindow) onShowModel() {
+	name, ok := w.selectedModel()
+	if !ok {
+		return
+	}
+
+	info, err := w.client.ShowModel(name)
+	if err != nil {
+		dialog.ShowError(err, w.window)
+		return
+	}
+
+	template := widget.NewMultiLineEntry()
+	template.SetText(info.Template)
+	template.Disable()
+	parameters := widget.NewMultiLineEntry()
+	parameters.SetText(info.Parameters)
+	parameters.Disable()
+
+	form := widget.NewForm(
+		widget.NewFormItem("名称", widget.NewLabel(info.Name)),
+		widget.NewFormItem("系列", widget.NewLabel(info.Family)),
+		widget.NewFormItem("参数规模", widget.NewLabel(info.ParameterSize)),
+		widget.NewFormItem("量化方式", widget.NewLabel(info.Quantization)),
+		widget.NewFormItem("上下文长度", widget.NewLabel(fmt.Sprintf("%d", info.ContextLength))),
+		widget.NewFormItem("参数", parameters),
+		widget.NewFormItem("模板", template),
+	)
+	d := dialog.NewCustom("模型详情", "关闭", container.NewVScroll(form), w.window)
+	d.Resize(fyne.NewSize(500, 450))
+	d.Show()
+}
+
+func (w *ModelWindow) onCopyModel() {
+	name, ok := w.selectedModel()
+	if !ok {
+		return
+	}
+
+	target := widget.NewEntry()
+	target.SetText(name + "-copy")
+	dialog.ShowForm("复制模型", "复制", "取消", []*widget.FormItem{
+		widget.NewFormItem("新名称", target),
+	}, func(confirm bool) {
+		if !confirm || strings.TrimSpace(target.Text) == "" {
+			return
+		}
+		if err := w.client.CopyModel(name, strings.TrimSpace(target.Text)); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.refreshModels()
+		w.mainWindow.refreshModelList()
+	}, w.window)
+}
+
+func (w *ModelWindow) onDeleteModel() {
+	name, ok := w.selectedModel()
+	if !ok {
+		return
+	}
+
+	dialog.ShowConfirm("确认", fmt.Sprintf("确定要删除模型 %s 吗？", name), func(b bool) {
+		if !b {
+			return
+		}
+		if err := w.client.DeleteModel(name); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.refreshModels()
+		w.mainWindow.refreshModelList()
+	}, w.window)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
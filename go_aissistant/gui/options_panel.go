@@ -0,0 +1,232 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/ai_model"
+)
+
+// 手动调整参数后预设选择器显示的名称
+const customPreset = "自定义"
+
+// buildOptionsPanel 构建模型选择器旁的参数面板：预设选择 + 参数调整按钮
+func (mw *MainWindow) buildOptionsPanel() fyne.CanvasObject {
+	mw.presetSelect = widget.NewSelect(mw.presetNames(), func(name string) {
+		if p, ok := mw.config.GenerationPresets[name]; ok {
+			mw.setGenOptions(ai_model.NewOptions(p))
+		}
+	})
+	if mw.config.DefaultPreset != "" {
+		mw.presetSelect.SetSelected(mw.config.DefaultPreset)
+	} else {
+		mw.setGenOptions(ai_model.DefaultOptions())
+	}
+
+	return container.NewHBox(
+		widget.NewLabel("参数:"),
+		mw.presetSelect,
+		widget.NewButtonWithIcon("调整", theme.SettingsIcon(), mw.showOptionsDialog),
+	)
+}
+
+func (mw *MainWindow) presetNames() []string {
+	names := make([]string, 0, len(mw.config.GenerationPresets)+1)
+	for name := range mw.config.GenerationPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, customPreset)
+}
+
+func (mw *MainWindow) setGenOptions(opts *ai_model.Options) {
+	mw.optionsMu.Lock()
+	defer mw.optionsMu.Unlock()
+	mw.genOptions = opts
+}
+
+// currentOptions 返回本次请求使用的参数副本
+func (mw *MainWindow) currentOptions() *ai_model.Options {
+	mw.optionsMu.Lock()
+	defer mw.optionsMu.Unlock()
+	if mw.genOptions == nil {
+		return ai_model.DefaultOptions()
+	}
+	opts := *mw.genOptions
+	opts.Stop = append([]string(nil), mw.genOptions.Stop...)
+	return &opts
+}
+
+// showOptionsDialog 弹出完整参数表单，可应用到当前对话或保存为新预设
+func (mw *MainWindow) showOptionsDialog() {
+	opts := mw.currentOptions()
+
+	temperature := newNumberEntry(formatFloat(opts.Temperature))
+	topP := newNumberEntry(formatFloat(opts.TopP))
+	topK := newIntEntry(formatInt(opts.TopK))
+	numCtx := newIntEntry(formatInt(opts.NumCtx))
+	numPredict := newIntEntry(formatInt(opts.NumPredict))
+	repeatPenalty := newNumberEntry(formatFloat(opts.RepeatPenalty))
+	seed := newIntEntry(formatInt(opts.Seed))
+	mirostat := widget.NewSelect([]string{"0", "1", "2"}, nil)
+	mirostat.PlaceHolder = "默认"
+	if opts.Mirostat != nil {
+		mirostat.SetSelected(strconv.Itoa(*opts.Mirostat))
+	}
+	mirostatTau := newNumberEntry(formatFloat(opts.MirostatTau))
+	mirostatEta := newNumberEntry(formatFloat(opts.MirostatEta))
+	stop := widget.NewEntry()
+	stop.SetText(strings.Join(opts.Stop, ","))
+	stop.SetPlaceHolder("多个停止词用逗号分隔")
+	keepAlive := widget.NewEntry()
+	keepAlive.SetText(opts.KeepAlive)
+	keepAlive.SetPlaceHolder("如 5m、1h、-1")
+	presetName := widget.NewEntry()
+	presetName.SetPlaceHolder("填写名称则同时保存为预设")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("temperature", temperature),
+		widget.NewFormItem("top_p", topP),
+		widget.NewFormItem("top_k", topK),
+		widget.NewFormItem("num_ctx", numCtx),
+		widget.NewFormItem("num_predict", numPredict),
+		widget.NewFormItem("repeat_penalty", repeatPenalty),
+		widget.NewFormItem("seed", seed),
+		widget.NewFormItem("stop", stop),
+		widget.NewFormItem("mirostat", mirostat),
+		widget.NewFormItem("mirostat_tau", mirostatTau),
+		widget.NewFormItem("mirostat_eta", mirostatEta),
+		widget.NewFormItem("keep_alive", keepAlive),
+		widget.NewFormItem("保存为预设", presetName),
+	}
+
+	d := dialog.NewForm("生成参数（留空表示使用模型默认值）", "应用", "取消", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+
+		var errs []string
+		floatField := func(name string, e *widget.Entry) *float32 {
+			v, err := parseFloat(e.Text)
+			if err != nil {
+				errs = append(errs, name+": "+err.Error())
+			}
+			return v
+		}
+		intField := func(name, text string) *int {
+			v, err := parseInt(text)
+			if err != nil {
+				errs = append(errs, name+": "+err.Error())
+			}
+			return v
+		}
+		newOpts := &ai_model.Options{
+			Temperature:   floatField("temperature", temperature),
+			TopP:          floatField("top_p", topP),
+			TopK:          intField("top_k", topK.Text),
+			NumCtx:        intField("num_ctx", numCtx.Text),
+			NumPredict:    intField("num_predict", numPredict.Text),
+			RepeatPenalty: floatField("repeat_penalty", repeatPenalty),
+			Seed:          intField("seed", seed.Text),
+			Mirostat:      intField("mirostat", mirostat.Selected),
+			MirostatTau:   floatField("mirostat_tau", mirostatTau),
+			MirostatEta:   floatField("mirostat_eta", mirostatEta),
+			KeepAlive:     strings.TrimSpace(keepAlive.Text),
+		}
+		if len(errs) > 0 {
+			dialog.ShowError(fmt.Errorf("参数格式错误，未应用:\n%s", strings.Join(errs, "\n")), mw.window)
+			return
+		}
+		for _, s := range strings.Split(stop.Text, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				newOpts.Stop = append(newOpts.Stop, s)
+			}
+		}
+
+		name := strings.TrimSpace(presetName.Text)
+		if name == "" || name == customPreset {
+			mw.presetSelect.SetSelected(customPreset)
+			mw.setGenOptions(newOpts)
+			return
+		}
+
+		if mw.config.GenerationPresets == nil {
+			mw.config.GenerationPresets = map[string]config.GenerationPreset{}
+		}
+		mw.config.GenerationPresets[name] = newOpts.Preset()
+		if err := config.SaveConfig(mw.config); err != nil {
+			dialog.ShowError(fmt.Errorf("保存预设时出错: %v", err), mw.window)
+		}
+		mw.presetSelect.Options = mw.presetNames()
+		mw.presetSelect.SetSelected(name)
+	}, mw.window)
+	d.Resize(fyne.NewSize(420, 560))
+	d.Show()
+}
+
+func newNumberEntry(text string) *widget.Entry {
+	e := widget.NewEntry()
+	e.SetText(text)
+	e.Validator = func(s string) error {
+		_, err := parseFloat(s)
+		return err
+	}
+	return e
+}
+
+// newIntEntry 只接受整数的输入框，用于 top_k、seed 等整数参数
+func newIntEntry(text string) *widget.Entry {
+	e := widget.NewEntry()
+	e.SetText(text)
+	e.Validator = func(s string) error {
+		_, err := parseInt(s)
+		return err
+	}
+	return e
+}
+
+func formatFloat(v *float32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*v), 'f', -1, 32)
+}
+
+func formatInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// parseFloat 解析数字参数，留空时返回 nil 表示使用模型默认值
+func parseFloat(s string) (*float32, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return nil, fmt.Errorf("请输入数字")
+	}
+	return ai_model.Float32(float32(v)), nil
+}
+
+// parseInt 解析整数参数，留空时返回 nil 表示使用模型默认值
+func parseInt(s string) (*int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("请输入整数")
+	}
+	return ai_model.Int(v), nil
+}
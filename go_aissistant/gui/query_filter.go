@@ -0,0 +1,167 @@
+package gui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fighthorse/aicode/go_aissistant/config"
+	"github.com/fighthorse/aicode/go_aissistant/core/knowledgebase"
+)
+
+// buildFilterBar 构建知识库过滤条件栏：筛选按钮和当前生效的条件，点击条件可以单独移除
+func (mw *MainWindow) buildFilterBar() fyne.CanvasObject {
+	mw.filterChips = container.NewHBox()
+	mw.refreshFilterChips()
+	return container.NewHBox(
+		widget.NewButtonWithIcon("筛选", theme.SearchIcon(), mw.showFilterDialog),
+		mw.filterChips,
+	)
+}
+
+// queryOptions 当前对话的检索选项，启用重排或多样化时检索 rerank_candidates 条候选
+func (mw *MainWindow) queryOptions() knowledgebase.QueryOptions {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+	opts := mw.kbFilter
+	opts.NumResults = mw.config.KBTopK
+	if mw.reranker != nil || mw.config.MMRLambda < 1 {
+		opts.NumResults = mw.config.RerankCandidates
+	}
+	opts.Collections = append([]string(nil), mw.kbCollections...)
+	return opts
+}
+
+func (mw *MainWindow) setFilter(filter knowledgebase.QueryOptions) {
+	mw.conversationMu.Lock()
+	mw.kbFilter = filter
+	mw.conversationMu.Unlock()
+	mw.refreshFilterChips()
+}
+
+func (mw *MainWindow) currentFilter() knowledgebase.QueryOptions {
+	mw.conversationMu.Lock()
+	defer mw.conversationMu.Unlock()
+	return mw.kbFilter
+}
+
+// refreshFilterChips 每个生效的条件显示为一个可移除的标签
+func (mw *MainWindow) refreshFilterChips() {
+	filter := mw.currentFilter()
+	mw.filterChips.RemoveAll()
+
+	chip := func(text string, clear func(*knowledgebase.QueryOptions)) {
+		button := widget.NewButtonWithIcon(text, theme.CancelIcon(), func() {
+			f := mw.currentFilter()
+			clear(&f)
+			mw.setFilter(f)
+		})
+		button.Importance = widget.LowImportance
+		mw.filterChips.Add(button)
+	}
+
+	if len(filter.Sources) > 0 {
+		chip("来源: "+strings.Join(filter.Sources, ", "), func(f *knowledgebase.QueryOptions) { f.Sources = nil })
+	}
+	if filter.DateFrom != "" || filter.DateTo != "" {
+		chip(fmt.Sprintf("日期: %s ~ %s", filter.DateFrom, filter.DateTo), func(f *knowledgebase.QueryOptions) {
+			f.DateFrom, f.DateTo = "", ""
+		})
+	}
+	if len(filter.Tags) > 0 {
+		chip("标签: "+strings.Join(filter.Tags, ", "), func(f *knowledgebase.QueryOptions) { f.Tags = nil })
+	}
+	if len(filter.FileTypes) > 0 {
+		chip("类型: "+strings.Join(filter.FileTypes, ", "), func(f *knowledgebase.QueryOptions) { f.FileTypes = nil })
+	}
+	if filter.MinScore > 0 {
+		chip(fmt.Sprintf("相似度 ≥ %.2f", filter.MinScore), func(f *knowledgebase.QueryOptions) { f.MinScore = 0 })
+	}
+	if filter.Mode != "" {
+		chip("检索方式: "+filter.Mode, func(f *knowledgebase.QueryOptions) { f.Mode = "" })
+	}
+	if len(mw.filterChips.Objects) == 0 {
+		mw.filterChips.Add(widget.NewLabel("未设置过滤条件"))
+	}
+	mw.filterChips.Refresh()
+}
+
+// showFilterDialog 编辑知识库检索的过滤条件，多个值用逗号分隔
+func (mw *MainWindow) showFilterDialog() {
+	filter := mw.currentFilter()
+
+	sources := widget.NewEntry()
+	sources.SetPlaceHolder("文件名，如 manual.pdf")
+	sources.SetText(strings.Join(filter.Sources, ", "))
+	dateFrom := widget.NewEntry()
+	dateFrom.SetPlaceHolder("2006-01-02")
+	dateFrom.SetText(filter.DateFrom)
+	dateTo := widget.NewEntry()
+	dateTo.SetPlaceHolder("2006-01-02")
+	dateTo.SetText(filter.DateTo)
+	tags := widget.NewEntry()
+	tags.SetText(strings.Join(filter.Tags, ", "))
+	fileTypes := widget.NewEntry()
+	fileTypes.SetPlaceHolder("如 pdf, md")
+	fileTypes.SetText(strings.Join(filter.FileTypes, ", "))
+
+	minScore := widget.NewSlider(0, 1)
+	minScore.Step = 0.05
+	minScore.SetValue(float64(filter.MinScore))
+	minScoreLabel := widget.NewLabel("")
+	minScore.OnChanged = func(v float64) {
+		minScoreLabel.SetText(strconv.FormatFloat(v, 'f', 2, 64))
+	}
+	minScore.OnChanged(minScore.Value)
+
+	modes := map[string]string{
+		"默认":   "",
+		"向量检索": config.RetrievalVector,
+		"关键词":  config.RetrievalKeyword,
+		"混合检索": config.RetrievalHybrid,
+	}
+	mode := widget.NewSelect([]string{"默认", "向量检索", "关键词", "混合检索"}, nil)
+	mode.SetSelected("默认")
+	for label, m := range modes {
+		if m == filter.Mode {
+			mode.SetSelected(label)
+		}
+	}
+
+	dialog.ShowForm("知识库过滤条件", "确定", "取消", []*widget.FormItem{
+		widget.NewFormItem("来源", sources),
+		widget.NewFormItem("起始日期", dateFrom),
+		widget.NewFormItem("截止日期", dateTo),
+		widget.NewFormItem("标签", tags),
+		widget.NewFormItem("文件类型", fileTypes),
+		widget.NewFormItem("最低相似度", container.NewBorder(nil, nil, nil, minScoreLabel, minScore)),
+		widget.NewFormItem("检索方式", mode),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		for _, date := range []string{dateFrom.Text, dateTo.Text} {
+			if date = strings.TrimSpace(date); date != "" {
+				if _, err := time.Parse("2006-01-02", date); err != nil {
+					dialog.ShowError(fmt.Errorf("日期格式应为 2006-01-02: %s", date), mw.window)
+					return
+				}
+			}
+		}
+		mw.setFilter(knowledgebase.QueryOptions{
+			Sources:   knowledgebase.ParsePatterns(sources.Text),
+			DateFrom:  strings.TrimSpace(dateFrom.Text),
+			DateTo:    strings.TrimSpace(dateTo.Text),
+			Tags:      knowledgebase.ParsePatterns(tags.Text),
+			FileTypes: knowledgebase.ParsePatterns(fileTypes.Text),
+			MinScore:  float32(minScore.Value),
+			Mode:      modes[mode.Selected],
+		})
+	}, mw.window)
+}
@@ -42,6 +42,9 @@ func (sw *SettingsWindow) buildUI() {
 	historyLimit := widget.NewEntry()
 	historyLimit.SetText(fmt.Sprintf("%d", sw.config.HistoryLimit))
 
+	parseExtras := widget.NewCheck("导入 Word/ODT 的页眉页脚、脚注和批注", nil)
+	parseExtras.SetChecked(sw.config.ParseExtras)
+
 	// 表单
 	sw.form = &widget.Form{
 		Items: []*widget.FormItem{
@@ -49,6 +52,7 @@ func (sw *SettingsWindow) buildUI() {
 			{Text: "Google API密钥", Widget: googleAPIKey},
 			{Text: "知识库存储路径", Widget: chromaPath},
 			{Text: "历史记录保留条数", Widget: historyLimit},
+			{Text: "文档附属内容", Widget: parseExtras},
 		},
 		OnSubmit: func() {
 			newOllamaURL := ollamaURL.Text
@@ -65,6 +69,7 @@ func (sw *SettingsWindow) buildUI() {
 			sw.config.GoogleAPIKey = newGoogleAPIKey
 			sw.config.ChromaPath = newChromaPath
 			sw.config.HistoryLimit = newHistoryLimit
+			sw.config.ParseExtras = parseExtras.Checked
 
 			// 保存配置
 			if err := config.SaveConfig(sw.config); err != nil {
@@ -0,0 +1,204 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fighthorse/aicode/go_aissistant/core/knowledgebase"
+	"github.com/fighthorse/aicode/go_aissistant/core/storage"
+	"github.com/fighthorse/aicode/go_aissistant/core/websearch"
+)
+
+// 保存到对话记录中的引用摘要长度
+const citationSnippetChars = 200
+
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// buildCitations 为知识库文档和网络搜索结果统一编号，知识库在前，编号与 buildPrompt 中的 [n] 一致
+func buildCitations(kbResults []knowledgebase.Document, webResults []websearch.SearchResult) []storage.Citation {
+	citations := make([]storage.Citation, 0, len(kbResults)+len(webResults))
+	for _, doc := range kbResults {
+		title, _ := doc.Metadata["source"].(string)
+		path, _ := doc.Metadata[knowledgebase.MetaPath].(string)
+		if title == "" {
+			title = filepath.Base(path)
+		}
+		score := doc.Score
+		if doc.RerankScore > 0 {
+			score = doc.RerankScore
+		}
+		citations = append(citations, storage.Citation{
+			Index:   len(citations) + 1,
+			Kind:    storage.CitationKnowledge,
+			Title:   title,
+			Path:    path,
+			Page:    doc.Page(),
+			Snippet: snippet(doc.Text),
+			Score:   score,
+		})
+	}
+	for _, result := range webResults {
+		citations = append(citations, storage.Citation{
+			Index:   len(citations) + 1,
+			Kind:    storage.CitationWeb,
+			Title:   result.Title,
+			URL:     result.Link,
+			Snippet: snippet(result.Snippet),
+		})
+	}
+	return citations
+}
+
+// citedIndexes 回答中出现过的引用编号
+func citedIndexes(response string) map[int]bool {
+	cited := map[int]bool{}
+	for _, m := range citationPattern.FindAllStringSubmatch(response, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			cited[n] = true
+		}
+	}
+	return cited
+}
+
+func snippet(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > citationSnippetChars {
+		return string(runes[:citationSnippetChars]) + "…"
+	}
+	return string(runes)
+}
+
+func citationLabel(c storage.Citation) string {
+	label := fmt.Sprintf("[%d] %s", c.Index, c.Title)
+	if c.Page > 0 {
+		label += fmt.Sprintf(" 第%d页", c.Page)
+	}
+	if c.Kind == storage.CitationWeb {
+		if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+			label += " · " + u.Host
+		}
+	} else if c.Score > 0 {
+		label += fmt.Sprintf(" · 相关度 %.2f", c.Score)
+	}
+	return label
+}
+
+// buildSourcesPanel 回答下方的参考来源，没有来源时隐藏
+func (mw *MainWindow) buildSourcesPanel() fyne.CanvasObject {
+	mw.sourcesList = container.NewVBox()
+	mw.sourcesPanel = container.NewVBox(widget.NewLabel("参考来源"), mw.sourcesList)
+	mw.sourcesPanel.Hide()
+	return mw.sourcesPanel
+}
+
+// showSources 显示本轮回答的参考来源，回答中引用过的排在前面
+func (mw *MainWindow) showSources(citations []storage.Citation, response string) {
+	mw.sourcesList.RemoveAll()
+	for _, obj := range mw.citationButtons(citations, response) {
+		mw.sourcesList.Add(obj)
+	}
+	if len(citations) > 0 {
+		mw.sourcesPanel.Show()
+	} else {
+		mw.sourcesPanel.Hide()
+	}
+	mw.sourcesPanel.Refresh()
+}
+
+// citationButtons 每个来源一个按钮，点击打开原文件（定位到页码）或网页；回答中引用过的高亮并排在前面
+func (mw *MainWindow) citationButtons(citations []storage.Citation, response string) []fyne.CanvasObject {
+	cited := citedIndexes(response)
+	var first, rest []fyne.CanvasObject
+	for _, c := range citations {
+		c := c
+		icon := theme.FileIcon()
+		if c.Kind == storage.CitationWeb {
+			icon = theme.SearchIcon()
+		}
+		button := widget.NewButtonWithIcon(citationLabel(c), icon, func() { mw.openCitation(c) })
+		button.Alignment = widget.ButtonAlignLeading
+		if cited[c.Index] {
+			button.Importance = widget.HighImportance
+			first = append(first, button)
+		} else {
+			button.Importance = widget.LowImportance
+			rest = append(rest, button)
+		}
+	}
+	return append(first, rest...)
+}
+
+// openCitation 打开引用的网页或原文件
+func (mw *MainWindow) openCitation(c storage.Citation) {
+	if c.Kind == storage.CitationWeb {
+		u, err := url.Parse(c.URL)
+		if err != nil || u.Scheme == "" {
+			mw.statusLabel.SetText("无效的链接: " + c.URL)
+			return
+		}
+		if err := mw.app.OpenURL(u); err != nil {
+			mw.statusLabel.SetText("打开链接失败: " + err.Error())
+		}
+		return
+	}
+	if c.Path == "" {
+		mw.statusLabel.SetText(c.Title + " 没有记录原文件路径，请重新导入")
+		return
+	}
+	if err := mw.openFile(c.Path, c.Page); err != nil {
+		mw.statusLabel.SetText("打开文件失败: " + err.Error())
+	}
+}
+
+// pdfViewers 支持指定页码的常见 PDF 阅读器及其参数
+var pdfViewers = map[string][]struct {
+	name string
+	args func(path string, page int) []string
+}{
+	"linux": {
+		{"evince", func(p string, n int) []string { return []string{"--page-index=" + strconv.Itoa(n), p} }},
+		{"okular", func(p string, n int) []string { return []string{"-p", strconv.Itoa(n), p} }},
+		{"zathura", func(p string, n int) []string { return []string{"-P", strconv.Itoa(n), p} }},
+	},
+	"windows": {
+		{"SumatraPDF", func(p string, n int) []string { return []string{"-page", strconv.Itoa(n), p} }},
+	},
+}
+
+// openFile 用系统默认程序打开文件；PDF 有页码时优先使用可以跳转到该页的阅读器，
+// 没有安装时以 file://...#page=N 打开，浏览器等支持该写法的程序会定位到该页
+func (mw *MainWindow) openFile(path string, page int) error {
+	if page > 0 && strings.EqualFold(filepath.Ext(path), ".pdf") {
+		for _, viewer := range pdfViewers[runtime.GOOS] {
+			if bin, err := exec.LookPath(viewer.name); err == nil {
+				cmd := exec.Command(bin, viewer.args(path, page)...)
+				if err := cmd.Start(); err == nil {
+					go cmd.Wait()
+					return nil
+				}
+				log.Printf("启动 %s 失败: %v", viewer.name, err)
+			}
+		}
+	}
+
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed // Windows 盘符路径
+	}
+	u := &url.URL{Scheme: "file", Path: slashed}
+	if page > 0 {
+		u.Fragment = "page=" + strconv.Itoa(page)
+	}
+	return mw.app.OpenURL(u)
+}
@@ -1,11 +1,70 @@
 package gui
 
 import (
+	"strings"
+	"sync"
+
 	"fyne.io/fyne/v2"
-	"github.com/fighthorse/aicode/go_aissistant/config"
+	"fyne.io/fyne/v2/widget"
 )
 
+// StreamingLabel 支持逐段追加文本的标签，用于实时展示模型的流式输出
 type StreamingLabel struct {
-	app    fyne.App
-	config *config.AppConfig
+	widget.Label
+
+	mu      sync.Mutex
+	builder strings.Builder
+
+	// OnChanged 文本变化后回调，可用于滚动到底部
+	OnChanged func()
+}
+
+func NewStreamingLabel() *StreamingLabel {
+	l := &StreamingLabel{}
+	l.Wrapping = fyne.TextWrapWord
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// Append 追加一段文本并刷新显示
+func (l *StreamingLabel) Append(text string) {
+	if text == "" {
+		return
+	}
+	l.mu.Lock()
+	l.builder.WriteString(text)
+	content := l.builder.String()
+	l.mu.Unlock()
+
+	l.Label.SetText(content)
+	l.changed()
+}
+
+// SetText 替换全部文本
+func (l *StreamingLabel) SetText(text string) {
+	l.mu.Lock()
+	l.builder.Reset()
+	l.builder.WriteString(text)
+	l.mu.Unlock()
+
+	l.Label.SetText(text)
+	l.changed()
+}
+
+// Reset 清空文本
+func (l *StreamingLabel) Reset() {
+	l.SetText("")
+}
+
+// Content 返回当前已累计的文本
+func (l *StreamingLabel) Content() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.builder.String()
+}
+
+func (l *StreamingLabel) changed() {
+	if l.OnChanged != nil {
+		l.OnChanged()
+	}
 }
@@ -10,6 +10,12 @@ import (
 	"github.com/fighthorse/aicode/go_aissistant/core/storage"
 	"github.com/fighthorse/aicode/go_aissistant/core/websearch"
 	"github.com/fighthorse/aicode/go_aissistant/gui"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2/app"
@@ -26,8 +32,63 @@ func main() {
 
 	// 使用命令行参数选择启动模式
 	mode := flag.String("mode", "gui", "选择启动模式: gui 或 cli")
+	reembed := flag.Bool("reembed", false, "更换向量模型后，使用当前模型重建知识库向量后退出")
+	importPath := flag.String("import", "", "导入文件或文件夹到知识库后退出，支持 txt、pdf、docx、odt、rtf、epub、pptx、md、html、csv、xlsx、json 和源码文件")
+	collection := flag.String("collection", "", "-import、-history 使用的集合，默认为配置中的 collection_name；-query 可以用逗号分隔多个集合")
+	include := flag.String("include", "", "-import 导入文件夹时只导入匹配的文件，逗号分隔，如 \"*.md,docs/**/*.pdf\"")
+	exclude := flag.String("exclude", "", "-import 导入文件夹时跳过匹配的文件和目录，逗号分隔，如 \"node_modules,*.min.js\"")
+	history := flag.String("history", "", "列出文件在知识库中的导入版本后退出，集合由 -collection 指定")
+	rollback := flag.Int("rollback", 0, "与 -history 一起使用，把该文件回滚到指定版本")
+	watch := flag.Bool("watch", false, "监听配置中 watch_folders 的文件夹，持续同步到知识库，Ctrl+C 退出")
+	query := flag.String("query", "", "检索知识库并输出结果和相似度后退出")
+	numResults := flag.Int("n", 5, "-query 返回的结果数")
+	source := flag.String("source", "", "-query 只检索这些来源文件，逗号分隔，如 \"manual.pdf,faq.md\"")
+	dateFrom := flag.String("from", "", "-query 导入日期下限，如 2024-01-01")
+	dateTo := flag.String("to", "", "-query 导入日期上限，如 2024-12-31")
+	tags := flag.String("tags", "", "-query 按标签过滤；与 -import 一起使用时为导入的文件添加标签，逗号分隔")
+	fileType := flag.String("type", "", "-query 按文件类型过滤，逗号分隔，如 \"pdf,md\"")
+	minScore := flag.Float64("min-score", 0, "-query 最低相似度（0-1），只作用于向量检索的结果")
+	rerank := flag.String("rerank", "", "-query 的重排方式 llm 或 embedding，默认为配置中的 rerank_mode")
+	retrieval := flag.String("retrieval", "", "-query 的检索方式 vector、keyword 或 hybrid，默认为配置中的 retrieval_mode")
 	flag.Parse()
 
+	if *reembed {
+		runReEmbed(cc)
+		return
+	}
+	if *query != "" {
+		if *rerank != "" {
+			cc.RerankMode = *rerank
+		}
+		runQuery(cc, *query, knowledgebase.QueryOptions{
+			NumResults:  *numResults,
+			Sources:     knowledgebase.ParsePatterns(*source),
+			DateFrom:    *dateFrom,
+			DateTo:      *dateTo,
+			Tags:        knowledgebase.ParsePatterns(*tags),
+			FileTypes:   knowledgebase.ParsePatterns(*fileType),
+			Collections: knowledgebase.ParsePatterns(*collection),
+			MinScore:    float32(*minScore),
+			Mode:        *retrieval,
+		})
+		return
+	}
+	if *history != "" {
+		runHistory(cc, *history, *collection, *rollback)
+		return
+	}
+	if *watch {
+		runWatch(cc)
+		return
+	}
+	if *importPath != "" {
+		runImport(cc, *importPath, *collection, knowledgebase.ParsePatterns(*tags), knowledgebase.FolderOptions{
+			Include: knowledgebase.ParsePatterns(*include),
+			Exclude: knowledgebase.ParsePatterns(*exclude),
+		})
+		return
+	}
+
 	fmt.Printf("启动模式: %s\n", *mode)
 	switch *mode {
 	case "gui":
@@ -65,6 +126,270 @@ func runGUI(cc *config.AppConfig) {
 	fyneApp.Run()
 }
 
+func runReEmbed(cc *config.AppConfig) {
+	kb, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+	if err := kb.ReEmbed(); err != nil {
+		fmt.Println("重新向量化失败:", err)
+		os.Exit(1)
+	}
+	fmt.Println("重新向量化完成")
+}
+
+func runImport(cc *config.AppConfig, path, collection string, tags []string, opts knowledgebase.FolderOptions) {
+	manager, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+	if collection == "" {
+		collection = manager.DefaultCollection()
+	}
+	kb, err := manager.EnsureCollection(collection)
+	if err != nil {
+		fmt.Println("打开集合失败:", err)
+		os.Exit(1)
+	}
+
+	importer := manager.Importer()
+	importer.Tags = tags
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Println("导入失败:", err)
+		os.Exit(1)
+	}
+	if info.IsDir() {
+		runImportFolder(importer, kb, path, collection, opts)
+		return
+	}
+
+	if !importer.Supported(path) {
+		fmt.Println("不支持的文件格式:", path)
+		os.Exit(1)
+	}
+	n, err := importer.ImportFile(kb, path)
+	if err != nil {
+		fmt.Println("导入失败:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已导入 %s 到集合 %s，共 %d 块\n", path, collection, n)
+}
+
+// runImportFolder 逐个输出文件的导入结果，Ctrl+C 时等正在处理的文件完成后停止
+func runImportFolder(importer *knowledgebase.Importer, kb knowledgebase.KnowledgeBaseI, root, collection string, opts knowledgebase.FolderOptions) {
+	files, err := importer.ScanFolder(root, opts)
+	if err != nil {
+		fmt.Println("导入失败:", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("文件夹中没有可导入的文件:", root)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var mu sync.Mutex
+	done, failed, chunks := 0, 0, 0
+	err = importer.ImportFiles(ctx, kb, files, func(r knowledgebase.ImportResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if r.Err != nil {
+			failed++
+			fmt.Printf("[%d/%d] 失败 %s: %v\n", done, len(files), r.Path, r.Err)
+			return
+		}
+		chunks += r.Chunks
+		fmt.Printf("[%d/%d] %s，%d 块\n", done, len(files), r.Path, r.Chunks)
+	})
+	if err != nil {
+		fmt.Println("导入已取消")
+	}
+	fmt.Printf("已导入 %d 个文件到集合 %s，共 %d 块，失败 %d 个\n", done-failed, collection, chunks, failed)
+	if err != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runQuery 按过滤条件检索知识库，启用重排时先取候选再重排，输出每条结果的得分、来源和内容摘要
+func runQuery(cc *config.AppConfig, query string, opts knowledgebase.QueryOptions) {
+	manager, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+	router, err := ai_model.NewRouter(cc)
+	if err != nil {
+		fmt.Println("初始化模型服务失败:", err)
+		os.Exit(1)
+	}
+	reranker, err := knowledgebase.NewRerankerFromConfig(cc, router)
+	if err != nil {
+		fmt.Println("初始化重排失败:", err)
+		os.Exit(1)
+	}
+
+	// 命令行没有对话历史，只做扩展和 HyDE
+	queries := []string{query}
+	if expander := knowledgebase.NewQueryExpanderFromConfig(cc, router); expander != nil {
+		if expander.Model == "" {
+			expander.Model = cc.DefaultModel
+		}
+		queries = expander.Expand(context.Background(), query, nil)
+		for _, q := range queries[1:] {
+			fmt.Println("扩展查询:", q)
+		}
+	}
+
+	topK := opts.NumResults
+	if reranker != nil || cc.MMRLambda < 1 {
+		opts.NumResults = max(cc.RerankCandidates, topK*4)
+	}
+	docs, err := manager.QueryMulti(queries, opts)
+	if err != nil {
+		fmt.Println("检索失败:", err)
+		os.Exit(1)
+	}
+	docs = knowledgebase.RerankDocuments(context.Background(), reranker, query, docs, len(docs))
+	docs = knowledgebase.Diversify(docs, topK, cc.MMRLambda)
+	if len(docs) == 0 {
+		fmt.Println("没有符合条件的结果")
+		return
+	}
+	for i, doc := range docs {
+		source, _ := doc.Metadata["source"].(string)
+		collection, _ := doc.Metadata[knowledgebase.MetaCollection].(string)
+		text := []rune(strings.Join(strings.Fields(doc.Text), " "))
+		if len(text) > 120 {
+			text = append(text[:120], []rune("…")...)
+		}
+		score := fmt.Sprintf("%.3f", doc.Score)
+		if reranker != nil {
+			score += fmt.Sprintf(" 重排 %.3f", doc.RerankScore)
+		}
+		fmt.Printf("%d. [%s] %s/%s\n   %s\n", i+1, score, collection, source, string(text))
+	}
+}
+
+// runHistory 列出文件的导入版本，rollback 大于 0 时回滚到该版本
+func runHistory(cc *config.AppConfig, path, collection string, rollback int) {
+	manager, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+	if collection == "" {
+		collection = manager.DefaultCollection()
+	}
+	kb, err := manager.Collection(collection)
+	if err != nil {
+		fmt.Println("打开集合失败:", err)
+		os.Exit(1)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	if rollback > 0 {
+		if err := manager.Versions().Rollback(kb, path, rollback); err != nil {
+			fmt.Println("回滚失败:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已将 %s 回滚到版本 %d\n", path, rollback)
+	}
+
+	versions, err := manager.Versions().History(collection, path)
+	if err != nil {
+		fmt.Println("查询版本失败:", err)
+		os.Exit(1)
+	}
+	if len(versions) == 0 {
+		fmt.Printf("集合 %s 中没有 %s 的版本记录\n", collection, path)
+		return
+	}
+	for i, v := range versions {
+		current := ""
+		if i == 0 {
+			current = "（当前）"
+		}
+		fmt.Printf("版本 %d  %s  %d 块  %.12s  %s%s\n", v.Version, v.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			v.Chunks, v.Hash, v.Note, current)
+	}
+}
+
+// runWatch 同步并监听 watch_folders 中的全部文件夹，直到 Ctrl+C
+func runWatch(cc *config.AppConfig) {
+	if len(cc.WatchFolders) == 0 {
+		fmt.Println("配置中没有 watch_folders")
+		os.Exit(1)
+	}
+	manager, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// 任一文件夹无法监听或监听异常停止时，退出码为 1
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for _, wf := range cc.WatchFolders {
+		collection := wf.Collection
+		if collection == "" {
+			collection = manager.DefaultCollection()
+		}
+		kb, err := manager.EnsureCollection(collection)
+		if err != nil {
+			fmt.Println("打开集合失败:", err)
+			failed.Store(true)
+			continue
+		}
+		importer := manager.Importer()
+		importer.Tags = wf.Tags
+		watcher, err := knowledgebase.NewFolderWatcher(importer, kb, wf.Path, knowledgebase.FolderOptions{
+			Include: wf.Include,
+			Exclude: wf.Exclude,
+		})
+		if err != nil {
+			fmt.Println("监听文件夹失败:", err)
+			failed.Store(true)
+			continue
+		}
+		watcher.OnSync = func(ev knowledgebase.SyncEvent) {
+			if ev.Err != nil {
+				fmt.Printf("[%s] %s失败 %s: %v\n", collection, ev.Action, ev.Path, ev.Err)
+				return
+			}
+			if ev.Action == knowledgebase.SyncRemoved {
+				fmt.Printf("[%s] %s %s\n", collection, ev.Action, ev.Path)
+				return
+			}
+			fmt.Printf("[%s] %s %s，%d 块\n", collection, ev.Action, ev.Path, ev.Chunks)
+		}
+
+		fmt.Printf("监听 %s -> 集合 %s\n", watcher.Root(), collection)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := watcher.Run(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("监听 %s 已停止: %v\n", watcher.Root(), err)
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() {
+		os.Exit(1)
+	}
+}
+
 func runCLI(cc *config.AppConfig) {
 	// 加载配置
 	cc, err := config.LoadConfig("./config/app.json")
@@ -73,7 +398,7 @@ func runCLI(cc *config.AppConfig) {
 	}
 
 	// 导入文件到知识库
-	parser := knowledgebase.NewFileParser()
+	parser := knowledgebase.NewFileParserFromConfig(cc)
 	content, _ := parser.ParseFile("document.pdf")
 	doc := knowledgebase.Document{
 		ID:   "doc1",
@@ -83,14 +408,20 @@ func runCLI(cc *config.AppConfig) {
 			"date":   time.Now().Format("2006-01-02"),
 		},
 	}
-	kb, _ := knowledgebase.NewChromaKB(cc)
-	kb.AddDocuments([]knowledgebase.Document{doc})
+	kb, err := knowledgebase.NewKnowledgeBaseManager(cc)
+	if err != nil {
+		fmt.Println("初始化知识库失败:", err)
+		os.Exit(1)
+	}
+	if err := kb.AddDocuments(knowledgebase.NewChunkerFromConfig(cc).Split(doc)); err != nil {
+		fmt.Println("导入文档失败:", err)
+	}
 
 	// 处理用户查询
 	userQuery := "Go语言的主要特性是什么？"
 
 	// 知识库检索
-	kbResults, _ := kb.Query(userQuery, 3)
+	kbResults, _ := kb.Query(userQuery, knowledgebase.QueryOptions{NumResults: 3})
 
 	// 网络搜索
 	searchClient := websearch.NewGoogleSearchClient("", "")
@@ -101,9 +432,9 @@ func runCLI(cc *config.AppConfig) {
 
 	// 生成回答
 	aiClient := ai_model.NewOllamaClient("")
-	response, _ := aiClient.Generate(prompt, "llama2")
+	response, _ := aiClient.Generate(prompt, "llama2", nil)
 
 	// 保存记录
 	sto, _ := storage.NewSQLiteStorage("")
-	sto.SaveChatRecord(userQuery, response, "llama2")
+	sto.SaveChatRecord(userQuery, response, "llama2", nil)
 }